@@ -0,0 +1,56 @@
+package ansi
+
+import (
+	"errors"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+// adfColors are the entries of the 64 color ArtWorx palette used for the
+// 16 text mode colors in VGA attribute order. They're the EGA defaults.
+var adfColors = [16]int{0, 1, 2, 3, 4, 5, 20, 7, 56, 57, 58, 59, 60, 61, 62, 63}
+
+const (
+	adfPaletteSize = 64 * 3
+	adfFontSize    = 256 * 16
+	adfWidth       = 80
+)
+
+// ReadADF reads an ArtWorx ADF file. It has a 64 color palette of which 16
+// are used, an 8x16 font, and 80 columns of character and attribute pairs
+// always using iCE colors.
+func ReadADF(r io.Reader) (*Document, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b, _ = splitSauce(b)
+	if len(b) < 1+adfPaletteSize+adfFontSize {
+		return nil, errors.New("ADF file too short")
+	}
+	pal64 := b[1 : 1+adfPaletteSize]
+	doc := &Document{
+		Palette:   make(Palette, 16),
+		Font:      &Font{Width: 8, Height: 16, Bitmap: append([]byte(nil), b[1+adfPaletteSize:1+adfPaletteSize+adfFontSize]...)},
+		ICEColors: true,
+	}
+	for i, n := range adfColors {
+		c := pal64[n*3 : n*3+3]
+		doc.Palette[vgaToANSI[i]] = color.RGBA{R: scale6Bit(c[0]), G: scale6Bit(c[1]), B: scale6Bit(c[2]), A: 255}
+	}
+	doc.Image = readAttrPairs(b[1+adfPaletteSize+adfFontSize:], adfWidth, true)
+	return doc, nil
+}
+
+// readAttrPairs returns an image of the character and attribute pairs in
+// b. A partial last row is padded with zero pixels.
+func readAttrPairs(b []byte, width int, ice bool) *Image {
+	n := len(b) / 2
+	height := (n + width - 1) / width
+	img := &Image{Width: width, Height: height, Pix: make([]Pixel, width*height)}
+	for i := 0; i < n; i++ {
+		img.Pix[i] = attrPixel(b[i*2], b[i*2+1], ice)
+	}
+	return img
+}
@@ -0,0 +1,34 @@
+package ansi
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+func TestReadADF(t *testing.T) {
+	b := []byte{1}
+	pal := make([]byte, 64*3)
+	pal[20*3], pal[20*3+1], pal[20*3+2] = 42, 21, 0 // brown
+	pal[63*3], pal[63*3+1], pal[63*3+2] = 63, 63, 63
+	b = append(b, pal...)
+	b = append(b, VGAFont16[:]...)
+	b = append(b, 'A', 0xff, 'B', 0x06)
+
+	doc, err := ReadADF(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Image.Width != 80 || doc.Image.Height != 1 {
+		t.Fatalf("Expected 80x1, got %dx%d", doc.Image.Width, doc.Image.Height)
+	}
+	if p := doc.Image.Pix[0]; p.ForegroundColor != 15 || p.BackgroundColor != 15 || p.Blink != BlinkNone {
+		t.Errorf("Expected white on iCE white, got %+v", p)
+	}
+	if c := doc.Palette[3]; c != (color.RGBA{R: 170, G: 85, A: 255}) {
+		t.Errorf("Expected brown from palette entry 20, got %v", c)
+	}
+	if c := doc.Palette[15]; c != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("Expected white from palette entry 63, got %v", c)
+	}
+}
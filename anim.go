@@ -0,0 +1,186 @@
+package ansi
+
+import (
+	"image"
+	"image/gif"
+	"io"
+	"reflect"
+	"time"
+)
+
+// FrameCut selects when ParseAnimation takes a snapshot of the screen.
+type FrameCut byte
+
+const (
+	// CutOnClear takes a snapshot before each clear screen and at the end.
+	CutOnClear FrameCut = iota
+	// CutBytes takes a snapshot after every AnimationOptions.Bytes bytes of input.
+	CutBytes
+	// CutBaud takes snapshots at AnimationOptions.FrameRate of the screen as
+	// it would appear when received at AnimationOptions.Baud.
+	CutBaud
+)
+
+// AnimationOptions are the options for ParseAnimation.
+type AnimationOptions struct {
+	// Width and Height are the size of the emulated screen. The default is 80x25.
+	Width  int
+	Height int
+	Cut    FrameCut
+	// Bytes is the number of bytes per frame for CutBytes. The default is
+	// 1024. It's not used by CutBaud.
+	Bytes int
+	// Delay is the time between frames for CutOnClear and CutBytes. The default is 100ms.
+	Delay time.Duration
+	// Baud is the simulated line speed in bits per second for CutBaud. Each
+	// byte takes 10 bits (8N1). The default is 9600.
+	Baud int
+	// FrameRate is the number of frames per second for CutBaud. The default is 10.
+	FrameRate int
+}
+
+// Frame is a snapshot of the screen and how long it's shown.
+type Frame struct {
+	Image *Image
+	Delay time.Duration
+}
+
+func (o *AnimationOptions) withDefaults() AnimationOptions {
+	var a AnimationOptions
+	if o != nil {
+		a = *o
+	}
+	if a.Width <= 0 {
+		a.Width = defaultScreenWidth
+	}
+	if a.Height <= 0 {
+		a.Height = 25
+	}
+	if a.Bytes <= 0 {
+		a.Bytes = 1024
+	}
+	if a.Delay <= 0 {
+		a.Delay = 100 * time.Millisecond
+	}
+	if a.Baud <= 0 {
+		a.Baud = 9600
+	}
+	if a.FrameRate <= 0 {
+		a.FrameRate = 10
+	}
+	if a.Cut == CutBaud {
+		a.Delay = time.Second / time.Duration(a.FrameRate)
+	}
+	return a
+}
+
+// ParseAnimation plays back an ANSImation on an emulated fixed size screen
+// and returns snapshots of the screen. Consecutive identical snapshots are
+// merged into one frame.
+func ParseAnimation(r io.ByteReader, opts *AnimationOptions) ([]Frame, error) {
+	o := opts.withDefaults()
+	p := NewParser(r)
+	rend := NewScreenRenderer(o.Width, o.Height)
+
+	var frames []Frame
+	snapshot := func() {
+		img := rend.Image()
+		if n := len(frames); n != 0 && reflect.DeepEqual(frames[n-1].Image.Pix, img.Pix) {
+			frames[n-1].Delay += o.Delay
+			return
+		}
+		frames = append(frames, Frame{Image: img, Delay: o.Delay})
+	}
+
+	next := int64(o.Bytes)
+	// For CutBaud frame n ends at n/FrameRate seconds. Cutting by the time
+	// the input takes to arrive rather than a whole number of bytes per
+	// frame keeps playback at the right speed.
+	frame := int64(1)
+	frameEnd := func() time.Duration {
+		return time.Duration(frame) * time.Second / time.Duration(o.FrameRate)
+	}
+	dirty := false
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if c, ok := s.(Clear); ok && o.Cut == CutOnClear && dirty && c.Type >= ClearTypeScreen {
+			snapshot()
+			dirty = false
+		}
+		if err := rend.Apply(s); err != nil {
+			return nil, err
+		}
+		dirty = true
+		switch o.Cut {
+		case CutBytes:
+			for p.Offset() >= next {
+				snapshot()
+				dirty = false
+				next += int64(o.Bytes)
+			}
+		case CutBaud:
+			for ByteTime(p.Offset(), o.Baud) >= frameEnd() {
+				snapshot()
+				dirty = false
+				frame++
+			}
+		}
+	}
+	if dirty || len(frames) == 0 {
+		snapshot()
+	}
+	return frames, nil
+}
+
+// EncodeAnimationGIF writes frames as a looping animated GIF. FilterLinear
+// is ignored and 24-bit colors are replaced by the closest palette color
+// since GIF frames must be paletted.
+func EncodeAnimationGIF(w io.Writer, frames []Frame, opts *RenderOptions) error {
+	o := opts.withDefaults()
+	o.Filter = FilterNearest
+	o.ColorModel = ColorModelPaletted
+	anim := &gif.GIF{}
+	for _, f := range o.cursorFrames(frames) {
+		anim.Image = append(anim.Image, Render(f.Image, &f.opts).(*image.Paletted))
+		anim.Delay = append(anim.Delay, int(f.Delay/(10*time.Millisecond)))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// optsFrame is a frame with the options to render it with.
+type optsFrame struct {
+	Frame
+	opts RenderOptions
+}
+
+// cursorFrames returns frames with the options to render each one. With
+// CursorBlink frames showing the cursor are split at each toggle of the
+// fast blink rate. The blink keeps its phase from frame to frame.
+func (o *RenderOptions) cursorFrames(frames []Frame) []optsFrame {
+	var out []optsFrame
+	step := BlinkFast.period() / 2
+	var t time.Duration
+	for _, f := range frames {
+		if !o.CursorBlink || o.Cursor == CursorNone || !f.Image.cursorVisible() || f.Delay <= 0 {
+			out = append(out, optsFrame{f, *o})
+			t += f.Delay
+			continue
+		}
+		for end := t + f.Delay; t < end; {
+			next := (t/step + 1) * step
+			if next > end {
+				next = end
+			}
+			fo := *o
+			fo.cursorOff = (t/step)%2 != 0
+			out = append(out, optsFrame{Frame{Image: f.Image, Delay: next - t}, fo})
+			t = next
+		}
+	}
+	return out
+}
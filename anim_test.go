@@ -0,0 +1,243 @@
+package ansi
+
+import (
+	"bufio"
+	"bytes"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAnimation = "\x1b[2Jframe one\x1b[2J\x1b[5;10Hframe two\x1b[2J\x1b[1;31mframe three"
+
+func TestParseAnimationCutOnClear(t *testing.T) {
+	frames, err := ParseAnimation(bufio.NewReader(strings.NewReader(testAnimation)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("Expected 3 frames, got %d", len(frames))
+	}
+	for i, want := range []string{"frame one", "\n\n\n\n         frame two", "frame three"} {
+		img := frames[i].Image
+		if img.Width != 80 || img.Height != 25 {
+			t.Errorf("Frame %d: expected 80x25, got %dx%d", i, img.Width, img.Height)
+		}
+		if got := img.Text(nil); got != want {
+			t.Errorf("Frame %d: expected %q, got %q", i, want, got)
+		}
+	}
+}
+
+func TestParseAnimationCutBaud(t *testing.T) {
+	// 300 baud at 10 frames per second is 3 bytes per frame
+	frames, err := ParseAnimation(bufio.NewReader(strings.NewReader("abcdefghi")), &AnimationOptions{Cut: CutBaud, Baud: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("Expected 3 frames, got %d", len(frames))
+	}
+	if got := frames[1].Image.Text(nil); got != "abcdef" {
+		t.Errorf("Expected %q, got %q", "abcdef", got)
+	}
+	if frames[0].Delay != 100*time.Millisecond {
+		t.Errorf("Expected 100ms delay, got %s", frames[0].Delay)
+	}
+
+	// 1000 baud is 100 bytes per second so a third of a second isn't a
+	// whole number of bytes. The frames end after 34, 67, and 100 bytes.
+	frames, err = ParseAnimation(bufio.NewReader(strings.NewReader(strings.Repeat("a", 100))), &AnimationOptions{Cut: CutBaud, Baud: 1000, FrameRate: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("Expected 3 frames for one second of input, got %d", len(frames))
+	}
+	for i, n := range []int{34, 67, 100} {
+		if got := strings.Count(frames[i].Image.Text(nil), "a"); got != n {
+			t.Errorf("Frame %d: expected %d bytes, got %d", i, n, got)
+		}
+	}
+}
+
+func TestScreenRendererScroll(t *testing.T) {
+	seq, err := NewParser(bufio.NewReader(strings.NewReader("1\r\n2\r\n3\r\n4"))).ParseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := NewScreenRenderer(80, 3).RenderSequence(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Text(nil); got != "2\n3\n4" {
+		t.Errorf("Expected scrolled screen, got %q", got)
+	}
+}
+
+func TestScreenRendererClear(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"abc\r\ndef\r\nghi\x1b[2;2H\x1b[J", "abc\nd"},
+		{"abc\r\ndef\r\nghi\x1b[2;2H\x1b[1J", "\n  f\nghi"},
+		{"abc\r\ndef\r\nghi\x1b[3Jx", "x"},
+		{"abc\x1b[ux", "xbc"},
+		{"a\x1b[sb\x1b[u\x1b[ux", "xb"},
+	}
+	for _, test := range tests {
+		seq, err := NewParser(bufio.NewReader(strings.NewReader(test.src))).ParseAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := NewScreenRenderer(80, 3).RenderSequence(seq)
+		if err != nil {
+			t.Errorf("%q: %s", test.src, err)
+			continue
+		}
+		if got := img.Text(nil); got != test.want {
+			t.Errorf("%q: expected %q, got %q", test.src, test.want, got)
+		}
+	}
+
+	frames, err := ParseAnimation(bufio.NewReader(strings.NewReader("one\x1b[3Jtwo\x1b[u")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 {
+		t.Errorf("Expected ESC[3J to cut a frame, got %d frames", len(frames))
+	}
+}
+
+func TestEncodeAnimation(t *testing.T) {
+	frames, err := ParseAnimation(bufio.NewReader(strings.NewReader(testAnimation)), &AnimationOptions{Width: 20, Height: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeAnimationGIF(&buf, frames, nil); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 || anim.Delay[0] != 10 {
+		t.Errorf("Expected 3 GIF frames with 10cs delay, got %d with %v", len(anim.Image), anim.Delay)
+	}
+
+	buf.Reset()
+	if err := EncodeAnimationAPNG(&buf, frames, nil); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := readPNGChunks(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for _, c := range chunks {
+		counts[c.typ]++
+	}
+	if counts["acTL"] != 1 || counts["fcTL"] != 3 || counts["fdAT"] < 2 || counts["IEND"] != 1 {
+		t.Errorf("Unexpected APNG chunks %v", counts)
+	}
+	// The default image is the first frame
+	img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := img.Bounds().Size(); s.X != 160 || s.Y != 80 {
+		t.Errorf("Expected 160x80, got %dx%d", s.X, s.Y)
+	}
+}
+
+func TestEncodeAnimationCursorBlink(t *testing.T) {
+	img, err := Parse(strings.NewReader("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The cursor toggles every 8/70 of a second so 500ms is 5 frames
+	frames := []Frame{{Image: img, Delay: 500 * time.Millisecond}}
+	opts := &RenderOptions{Cursor: CursorBlock, CursorBlink: true}
+
+	var buf bytes.Buffer
+	if err := EncodeAnimationGIF(&buf, frames, opts); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 5 {
+		t.Fatalf("Expected 5 GIF frames, got %d", len(anim.Image))
+	}
+	for i, frame := range anim.Image {
+		want := uint8(7)
+		if i%2 != 0 {
+			want = 0
+		}
+		if c := frame.ColorIndexAt(20, 8); c != want {
+			t.Errorf("Frame %d: expected %d at the cursor, got %d", i, want, c)
+		}
+	}
+
+	buf.Reset()
+	if err := EncodeAnimationAPNG(&buf, frames, opts); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := readPNGChunks(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fctl int
+	for _, c := range chunks {
+		if c.typ == "fcTL" {
+			fctl++
+		}
+	}
+	if fctl != 5 {
+		t.Errorf("Expected 5 APNG frames, got %d", fctl)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	p := NewParser(bufio.NewReader(strings.NewReader("abcdefghij")))
+	var events []Event
+	var screens []string
+	err := NewScreenRenderer(80, 25).Replay(p, &ReplayOptions{Baud: 1000, Interval: 30 * time.Millisecond}, func(e Event) error {
+		events = append(events, e)
+		screens = append(screens, e.Screen().Text(nil))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1000 baud is 10ms per byte
+	var times []time.Duration
+	for _, e := range events {
+		times = append(times, e.Time)
+	}
+	want := []time.Duration{10, 30, 60, 90, 100}
+	if len(times) != len(want) {
+		t.Fatalf("Expected event times %v ms, got %v", want, times)
+	}
+	for i := range want {
+		if times[i] != want[i]*time.Millisecond {
+			t.Errorf("Expected event times %v ms, got %v", want, times)
+			break
+		}
+	}
+	if got := screens[0]; got != "a" {
+		t.Errorf("Expected first screen %q, got %q", "a", got)
+	}
+	if got := screens[len(screens)-1]; got != "abcdefghij" {
+		t.Errorf("Expected final screen %q, got %q", "abcdefghij", got)
+	}
+	if d := ByteTime(10, 0); d != 0 {
+		t.Errorf("Expected no time at 0 baud, got %s", d)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"image/color"
 	"io"
 	"strconv"
+	"time"
 )
 
 const (
@@ -39,13 +40,29 @@ type Image struct {
 	Pix    []Pixel
 	Width  int
 	Height int
+	// Cursor is the 0-based column and row of the cursor when HasCursor
+	// is set. Renderer.Image sets both; images read from other formats
+	// have no cursor.
+	Cursor       image.Point
+	CursorHidden bool
+	HasCursor    bool
 }
 
 type Pixel struct {
-	C               byte
+	C byte
+	// R, if not zero, is the Unicode character from UTF-8 input. C is
+	// then the PC ASCII character with the same glyph or '?'. Bitmap fonts
+	// draw C and RenderOptions.Face draws R.
+	R               rune
 	BackgroundColor byte
 	ForegroundColor byte
 	Blink           Blink
+	// ForegroundRGB and BackgroundRGB are 24-bit colors set with
+	// ESC[38;2;r;g;bm and ESC[48;2;r;g;bm (or ESC[38;5;nm for the xterm
+	// 256 colors). They're only used when the alpha is not zero, in which
+	// case ForegroundColor and BackgroundColor are the closest VGA colors.
+	ForegroundRGB color.RGBA
+	BackgroundRGB color.RGBA
 	// TODO: other attributes: ?
 }
 
@@ -57,9 +74,27 @@ const (
 	BlinkFast
 )
 
+// period returns the duration of a full on and off cycle. VGA text mode
+// toggles blinking characters every 16 frames at 70Hz. There's no hardware
+// equivalent for fast blink so it's twice the rate.
+func (b Blink) period() time.Duration {
+	switch b {
+	case BlinkSlow:
+		return 32 * time.Second / 70
+	case BlinkFast:
+		return 16 * time.Second / 70
+	}
+	return 0
+}
+
 type Parser struct {
-	r io.ByteReader
-	b byte
+	r       io.ByteReader
+	b       byte
+	offset  int64
+	pending []Sequence
+	bbs     BBSCodes
+	utf8    bool
+	unread  []byte // bytes read ahead that haven't been parsed
 }
 
 type Sequence interface {
@@ -67,6 +102,9 @@ type Sequence interface {
 
 type Character struct {
 	C byte
+	// R, if not zero, is the Unicode character decoded by a UTF-8 parser.
+	// C is then the PC ASCII character with the same glyph or '?'.
+	R rune
 }
 
 type CursorUp struct {
@@ -103,6 +141,33 @@ const (
 	ClearTypeScreenAndScrollback ClearType = 3
 )
 
+type EraseLine struct {
+	Type EraseLineType
+}
+
+type EraseLineType byte
+
+const (
+	EraseLineToEnd       EraseLineType = 0
+	EraseLineToBeginning EraseLineType = 1
+	EraseLineAll         EraseLineType = 2
+)
+
+// Private modes
+const (
+	ModeShowCursor = 25
+)
+
+type SetMode struct {
+	N       int
+	Private bool
+}
+
+type ResetMode struct {
+	N       int
+	Private bool
+}
+
 type SaveCursorPosition struct{}
 
 type RestoreCursorPosition struct{}
@@ -113,28 +178,47 @@ type SelectGraphicsRendition struct {
 
 type GraphicsRendition byte
 
+// SetTextColorRGB sets a 24-bit text color.
+type SetTextColorRGB struct {
+	C color.RGBA
+}
+
+// SetBackgroundColorRGB sets a 24-bit background color.
+type SetBackgroundColorRGB struct {
+	C color.RGBA
+}
+
 const (
-	GraphicsRenditionReset               GraphicsRendition = 0
-	GraphicsRenditionBold                GraphicsRendition = 1
-	GraphicRenditionBlinkSlow            GraphicsRendition = 5
-	GraphicRenditionBlinkFast            GraphicsRendition = 6
-	GraphicsRenditionSetTextColor0       GraphicsRendition = 30
-	GraphicsRenditionSetTextColor1       GraphicsRendition = 31
-	GraphicsRenditionSetTextColor2       GraphicsRendition = 32
-	GraphicsRenditionSetTextColor3       GraphicsRendition = 33
-	GraphicsRenditionSetTextColor4       GraphicsRendition = 34
-	GraphicsRenditionSetTextColor5       GraphicsRendition = 35
-	GraphicsRenditionSetTextColor6       GraphicsRendition = 36
-	GraphicsRenditionSetTextColor7       GraphicsRendition = 37
-	GraphicsrenditionDefaultTextColor    GraphicsRendition = 39
-	GraphicsRenditionSetBackgroundColor0 GraphicsRendition = 40
-	GraphicsRenditionSetBackgroundColor1 GraphicsRendition = 41
-	GraphicsRenditionSetBackgroundColor2 GraphicsRendition = 42
-	GraphicsRenditionSetBackgroundColor3 GraphicsRendition = 43
-	GraphicsRenditionSetBackgroundColor4 GraphicsRendition = 44
-	GraphicsRenditionSetBackgroundColor5 GraphicsRendition = 45
-	GraphicsRenditionSetBackgroundColor6 GraphicsRendition = 46
-	GraphicsRenditionSetBackgroundColor7 GraphicsRendition = 47
+	GraphicsRenditionReset                  GraphicsRendition = 0
+	GraphicsRenditionBold                   GraphicsRendition = 1
+	GraphicRenditionBlinkSlow               GraphicsRendition = 5
+	GraphicRenditionBlinkFast               GraphicsRendition = 6
+	GraphicsRenditionSetTextColor0          GraphicsRendition = 30
+	GraphicsRenditionSetTextColor1          GraphicsRendition = 31
+	GraphicsRenditionSetTextColor2          GraphicsRendition = 32
+	GraphicsRenditionSetTextColor3          GraphicsRendition = 33
+	GraphicsRenditionSetTextColor4          GraphicsRendition = 34
+	GraphicsRenditionSetTextColor5          GraphicsRendition = 35
+	GraphicsRenditionSetTextColor6          GraphicsRendition = 36
+	GraphicsRenditionSetTextColor7          GraphicsRendition = 37
+	GraphicsrenditionDefaultTextColor       GraphicsRendition = 39
+	GraphicsRenditionSetBackgroundColor0    GraphicsRendition = 40
+	GraphicsRenditionSetBackgroundColor1    GraphicsRendition = 41
+	GraphicsRenditionSetBackgroundColor2    GraphicsRendition = 42
+	GraphicsRenditionSetBackgroundColor3    GraphicsRendition = 43
+	GraphicsRenditionSetBackgroundColor4    GraphicsRendition = 44
+	GraphicsRenditionSetBackgroundColor5    GraphicsRendition = 45
+	GraphicsRenditionSetBackgroundColor6    GraphicsRendition = 46
+	GraphicsRenditionSetBackgroundColor7    GraphicsRendition = 47
+	GraphicsRenditionDefaultBackgroundColor GraphicsRendition = 49
+)
+
+// High intensity colors (aixterm)
+const (
+	GraphicsRenditionSetBrightTextColor0       GraphicsRendition = 90
+	GraphicsRenditionSetBrightTextColor7       GraphicsRendition = 97
+	GraphicsRenditionSetBrightBackgroundColor0 GraphicsRendition = 100
+	GraphicsRenditionSetBrightBackgroundColor7 GraphicsRendition = 107
 )
 
 func Parse(r io.ByteReader) (*Image, error) {
@@ -150,48 +234,55 @@ func NewParser(r io.ByteReader) *Parser {
 	return &Parser{r: r}
 }
 
+// RenderImage rasterizes the image using the default options. 24-bit
+// colors are replaced by the closest palette color.
 func RenderImage(ansiImage *Image) *image.Paletted {
-	const fontWidth = 8
-	const fontHeight = 16
+	return Render(ansiImage, &RenderOptions{ColorModel: ColorModelPaletted}).(*image.Paletted)
+}
 
-	palette := make([]color.Color, len(VGAPalette))
-	for i, c := range VGAPalette {
-		palette[i] = c
-	}
-	img := image.NewPaletted(image.Rect(0, 0, ansiImage.Width*fontWidth, ansiImage.Height*fontHeight), palette)
-	for y := 0; y < ansiImage.Height; y++ {
-		for x := 0; x < ansiImage.Width; x++ {
-			p := ansiImage.Pix[y*ansiImage.Width+x]
-			o := x*fontWidth + y*fontHeight*img.Bounds().Dx()
-			fc := VGAFont16[int(p.C)*16 : int(p.C)*16+16]
-			for fy := 0; fy < fontHeight; fy++ {
-				for fx := 0; fx < fontWidth; fx++ {
-					if (fc[fy]>>uint(fontWidth-1-fx))&1 == 0 {
-						img.Pix[o+fy*img.Bounds().Dx()+fx] = p.BackgroundColor
-					} else {
-						img.Pix[o+fy*img.Bounds().Dx()+fx] = p.ForegroundColor
-					}
-				}
-			}
+// ParseAll parses the remaining input until EOF.
+func (p *Parser) ParseAll() (seq []Sequence, err error) {
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			return seq, nil
+		} else if err != nil {
+			return seq, err
 		}
+		seq = append(seq, s)
 	}
-	return img
 }
 
-func (p *Parser) ParseAll() (seq []Sequence, err error) {
+// Next parses and returns the next sequence. At the end of the input, or
+// at the DOS EOF marker, it returns io.EOF.
+func (p *Parser) Next() (s Sequence, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if e, ok := r.(error); ok {
-				if e != io.EOF {
-					err = e
-				}
+				err = e
 			} else {
 				err = fmt.Errorf("runtime error: %v", r)
 			}
 		}
 	}()
 
-	for {
+	// Some escape sequences produce multiple sequences
+	if len(p.pending) == 0 {
+		p.pending = p.parse(p.pending[:0])
+	}
+	s = p.pending[0]
+	p.pending = p.pending[1:]
+	return s, nil
+}
+
+// Offset returns the number of bytes read from the input.
+func (p *Parser) Offset() int64 {
+	return p.offset
+}
+
+// parse parses until there's at least one sequence and appends the results to seq.
+func (p *Parser) parse(seq []Sequence) []Sequence {
+	for len(seq) == 0 {
 		p.next()
 		switch p.b {
 		case eof: // this should be optional
@@ -202,6 +293,11 @@ func (p *Parser) ParseAll() (seq []Sequence, err error) {
 				panic(fmt.Errorf("invalid escape sequence, expected 0x5b found 0x%02x", p.b))
 			}
 			p.next()
+			// Private modes such as ESC[?25l
+			private := p.b == '?'
+			if private {
+				p.next()
+			}
 			var nums []int
 		escLoop:
 			for {
@@ -263,6 +359,14 @@ func (p *Parser) ParseAll() (seq []Sequence, err error) {
 					nums = append(nums, 0)
 				}
 				seq = append(seq, Clear{Type: ClearType(nums[0])})
+			case 'K':
+				// Erases part of the line. If n is 0 (or missing), clear from cursor to
+				// the end of the line. If n is 1, clear from cursor to beginning of the
+				// line. If n is 2, clear entire line. Cursor position does not change.
+				if len(nums) == 0 {
+					nums = append(nums, 0)
+				}
+				seq = append(seq, EraseLine{Type: EraseLineType(nums[0])})
 			case 'm':
 				// Sets SGR parameters, including text color. After CSI can be zero or more parameters
 				// separated with ;. With no parameters, CSI m is treated as CSI 0 m (reset / normal),
@@ -270,9 +374,27 @@ func (p *Parser) ParseAll() (seq []Sequence, err error) {
 				if len(nums) == 0 {
 					nums = append(nums, 0)
 				}
-				for _, m := range nums {
+				for i := 0; i < len(nums); i++ {
+					m := nums[i]
+					if m == 38 || m == 48 {
+						if s, n := extendedColor(m == 48, nums[i+1:]); s != nil {
+							seq = append(seq, s)
+							i += n
+							continue
+						}
+					}
 					seq = append(seq, SelectGraphicsRendition{N: GraphicsRendition(m)})
 				}
+			case 'h', 'l':
+				// Sets or resets modes. The only one that's rendered is private
+				// mode 25 (DECTCEM) which shows or hides the cursor.
+				for _, n := range nums {
+					if ctrl == 'h' {
+						seq = append(seq, SetMode{N: n, Private: private})
+					} else {
+						seq = append(seq, ResetMode{N: n, Private: private})
+					}
+				}
 			case 's':
 				// Saves the cursor position.
 				seq = append(seq, SaveCursorPosition{})
@@ -283,20 +405,43 @@ func (p *Parser) ParseAll() (seq []Sequence, err error) {
 			default:
 				panic(fmt.Errorf("unknown escape sequence ESC[%+v%c", nums, ctrl))
 			}
+		case '@', '|':
+			seq = p.parseBBSCode(seq)
 		default:
+			if p.utf8 && p.b >= 0x80 {
+				seq = p.parseUTF8(seq)
+				break
+			}
 			seq = append(seq, Character{C: p.b})
 		}
 	}
+	return seq
 }
 
 func (p *Parser) next() {
-	b, err := p.r.ReadByte()
+	b, err := p.readByte()
 	if err != nil {
 		panic(err)
 	}
 	p.b = b
 }
 
+// readByte returns the next byte of input, starting with any read ahead.
+func (p *Parser) readByte() (byte, error) {
+	if len(p.unread) != 0 {
+		b := p.unread[0]
+		p.unread = p.unread[1:]
+		p.offset++
+		return b, nil
+	}
+	b, err := p.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	p.offset++
+	return b, nil
+}
+
 func (p *Parser) readNum() int {
 	var buf []byte
 	for p.b >= '0' && p.b <= '9' {
@@ -653,3 +798,59 @@ var PCASCIIToUnicode = [256]rune{
 	254: 0x25A0, // ■ : black square
 	255: 0x00A0, //   : no-break space
 }
+
+// pcASCIIGlyphs are the displayed glyphs for the control characters which
+// PCASCIIToUnicode maps to themselves.
+var pcASCIIGlyphs = [32]rune{
+	' ', '☺', '☻', '♥', '♦', '♣', '♠', '•', '◘', '○', '◙', '♂', '♀', '♪', '♫', '☼',
+	'►', '◄', '↕', '‼', '¶', '§', '▬', '↨', '↑', '↓', '→', '←', '∟', '↔', '▲', '▼',
+}
+
+// pcASCIIRune returns the printable rune for a PC ASCII character. Unlike
+// PCASCIIToUnicode control characters are mapped to the glyphs they
+// display as in text mode.
+func pcASCIIRune(c byte) rune {
+	switch {
+	case c < 32:
+		return pcASCIIGlyphs[c]
+	case c == 127:
+		return '⌂'
+	}
+	return PCASCIIToUnicode[c]
+}
+
+// extendedColor parses the parameters following 38 (text) or 48
+// (background) and returns the sequence and the number of parameters
+// used. The xterm 256 colors below 16 are returned as the equivalent
+// SelectGraphicsRendition. It returns nil if the parameters are invalid.
+func extendedColor(background bool, nums []int) (Sequence, int) {
+	switch {
+	case len(nums) >= 4 && nums[0] == 2:
+		for _, v := range nums[1:4] {
+			if v > 255 {
+				return nil, 0
+			}
+		}
+		c := color.RGBA{R: byte(nums[1]), G: byte(nums[2]), B: byte(nums[3]), A: 255}
+		if background {
+			return SetBackgroundColorRGB{C: c}, 4
+		}
+		return SetTextColorRGB{C: c}, 4
+	case len(nums) >= 2 && nums[0] == 5 && nums[1] <= 255:
+		n := nums[1]
+		switch {
+		case n < 8 && background:
+			return SelectGraphicsRendition{N: GraphicsRenditionSetBackgroundColor0 + GraphicsRendition(n)}, 2
+		case n < 8:
+			return SelectGraphicsRendition{N: GraphicsRenditionSetTextColor0 + GraphicsRendition(n)}, 2
+		case n < 16 && background:
+			return SelectGraphicsRendition{N: GraphicsRenditionSetBrightBackgroundColor0 + GraphicsRendition(n-8)}, 2
+		case n < 16:
+			return SelectGraphicsRendition{N: GraphicsRenditionSetBrightTextColor0 + GraphicsRendition(n-8)}, 2
+		case background:
+			return SetBackgroundColorRGB{C: xterm256Color(byte(n))}, 2
+		}
+		return SetTextColorRGB{C: xterm256Color(byte(n))}, 2
+	}
+	return nil, 0
+}
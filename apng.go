@@ -0,0 +1,146 @@
+package ansi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image/png"
+	"io"
+	"time"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// EncodeAnimationAPNG writes frames as a looping animated PNG.
+func EncodeAnimationAPNG(w io.Writer, frames []Frame, opts *RenderOptions) error {
+	if len(frames) == 0 {
+		return errors.New("no frames to encode")
+	}
+	o := opts.withDefaults()
+	if o.ColorModel == ColorModelAuto {
+		// All frames must have the same color type
+		o.ColorModel = ColorModelPaletted
+		for _, f := range frames {
+			if f.Image.hasRGB() {
+				o.ColorModel = ColorModelRGBA
+				break
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	var ihdr []byte
+	var seq uint32
+	out := &pngWriter{w: w}
+	rframes := o.cursorFrames(frames)
+	for i, f := range rframes {
+		buf.Reset()
+		if err := png.Encode(&buf, Render(f.Image, &f.opts)); err != nil {
+			return err
+		}
+		chunks, err := readPNGChunks(buf.Bytes())
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			ihdr = chunks[0].data
+			out.write(pngSignature)
+			out.writeChunk("IHDR", ihdr)
+			actl := make([]byte, 8)
+			binary.BigEndian.PutUint32(actl[0:4], uint32(len(rframes)))
+			out.writeChunk("acTL", actl) // 0 plays is infinite
+			for _, c := range chunks {
+				if c.typ == "PLTE" || c.typ == "tRNS" {
+					out.writeChunk(c.typ, c.data)
+				}
+			}
+		} else if !bytes.Equal(chunks[0].data, ihdr) {
+			return fmt.Errorf("frame %d has a different size or color type", i)
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:4], seq)
+		copy(fctl[4:12], ihdr[0:8]) // width, height
+		// x and y offsets are 0
+		delay := f.Delay / time.Millisecond
+		if delay > 0xffff {
+			delay = 0xffff
+		}
+		binary.BigEndian.PutUint16(fctl[20:22], uint16(delay))
+		binary.BigEndian.PutUint16(fctl[22:24], 1000)
+		// dispose_op and blend_op are 0 (none and source)
+		out.writeChunk("fcTL", fctl)
+		seq++
+
+		for _, c := range chunks {
+			if c.typ != "IDAT" {
+				continue
+			}
+			if i == 0 {
+				out.writeChunk("IDAT", c.data)
+				continue
+			}
+			fdat := make([]byte, 4+len(c.data))
+			binary.BigEndian.PutUint32(fdat[0:4], seq)
+			copy(fdat[4:], c.data)
+			out.writeChunk("fdAT", fdat)
+			seq++
+		}
+	}
+	out.writeChunk("IEND", nil)
+	return out.err
+}
+
+// readPNGChunks splits an encoded PNG into its chunks. IHDR is always first.
+func readPNGChunks(b []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(b, pngSignature) {
+		return nil, errors.New("invalid PNG signature")
+	}
+	b = b[len(pngSignature):]
+	var chunks []pngChunk
+	for len(b) >= 12 {
+		n := int(binary.BigEndian.Uint32(b[0:4]))
+		if len(b) < 12+n {
+			break
+		}
+		chunks = append(chunks, pngChunk{typ: string(b[4:8]), data: b[8 : 8+n]})
+		b = b[12+n:]
+	}
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || len(chunks[0].data) != 13 {
+		return nil, errors.New("invalid PNG chunks")
+	}
+	return chunks, nil
+}
+
+// pngWriter writes PNG chunks keeping the first error.
+type pngWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *pngWriter) write(b []byte) {
+	if p.err == nil {
+		_, p.err = p.w.Write(b)
+	}
+}
+
+func (p *pngWriter) writeChunk(typ string, data []byte) {
+	var hdr [8]byte
+	binary.BigEndian.PutUint32(hdr[0:4], uint32(len(data)))
+	copy(hdr[4:8], typ)
+	crc := crc32.NewIEEE()
+	crc.Write(hdr[4:8])
+	crc.Write(data)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	p.write(hdr[:])
+	p.write(data)
+	p.write(sum[:])
+}
@@ -0,0 +1,285 @@
+package ansi
+
+import (
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"runtime"
+)
+
+// BandOptions are the options for RenderBands and EncodePNG.
+type BandOptions struct {
+	// Rows is the number of rows of cells in each band. The default is 25.
+	Rows int
+	// Workers is the number of bands rasterized concurrently. The default
+	// is runtime.GOMAXPROCS(0). About Workers bands are in memory at once.
+	Workers int
+}
+
+func (opts *BandOptions) withDefaults() BandOptions {
+	var o BandOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Rows <= 0 {
+		o.Rows = 25
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	return o
+}
+
+// RenderBands rasterizes ansiImage in horizontal bands of cells using
+// several goroutines and calls fn with each band in order from the top.
+// The bounds of a band are its position in the full image. Bands are all
+// *image.Paletted or all *image.RGBA following the ColorModel, and aren't
+// stretched for AspectRatio. A band must not be used after fn returns.
+// RenderBands stops at the first error returned by fn.
+func RenderBands(ansiImage *Image, opts *RenderOptions, bandOpts *BandOptions, fn func(band draw.Image) error) error {
+	o := opts.withDefaults()
+	bo := bandOpts.withDefaults()
+	if o.ColorModel == ColorModelAuto {
+		o.ColorModel = ColorModelPaletted
+		if ansiImage.hasRGB() {
+			o.ColorModel = ColorModelRGBA
+		}
+	}
+	cells := o.cells(ansiImage)
+	o.Cells = cells
+	size := o.cellSize()
+	cellWidth := size.X
+	cellHeight := size.Y
+	pal := o.Palette.colors()
+
+	// Bands are queued in order as they're started so that at most
+	// Workers are rendering or waiting for fn.
+	queue := make(chan chan draw.Image, bo.Workers)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer close(queue)
+		for y := cells.Min.Y; y < cells.Max.Y; y += bo.Rows {
+			result := make(chan draw.Image, 1)
+			select {
+			case queue <- result:
+			case <-stop:
+				return
+			}
+			go func(y int) {
+				ro := o
+				ro.Cells = image.Rect(cells.Min.X, y, cells.Max.X, y+bo.Rows).Intersect(cells)
+				rect := image.Rect(0, 0, ro.Cells.Dx()*cellWidth, ro.Cells.Dy()*cellHeight).
+					Add(image.Pt(0, (y-cells.Min.Y)*cellHeight))
+				var img draw.Image
+				if o.ColorModel == ColorModelRGBA {
+					img = image.NewRGBA(rect)
+				} else {
+					img = image.NewPaletted(rect, pal)
+				}
+				drawCells(img, rect.Min, ansiImage, &ro)
+				result <- img
+			}(y)
+		}
+	}()
+	for result := range queue {
+		if err := fn(<-result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodePNG rasterizes ansiImage and writes it as a PNG. Unlike encoding
+// the result of Render the whole image is never held in memory and the
+// bands are rasterized concurrently. The output has the same pixels as
+// Render with the same options, including the AspectRatio and Filter.
+func EncodePNG(w io.Writer, ansiImage *Image, opts *RenderOptions, bandOpts *BandOptions) error {
+	o := opts.withDefaults()
+	if o.ColorModel == ColorModelAuto {
+		o.ColorModel = ColorModelPaletted
+		if ansiImage.hasRGB() {
+			o.ColorModel = ColorModelRGBA
+		}
+	}
+	cells := o.cells(ansiImage)
+	size := o.cellSize()
+	width := cells.Dx() * size.X
+	srcHeight := cells.Dy() * size.Y
+	if width == 0 || srcHeight == 0 {
+		return errors.New("cannot encode an empty image")
+	}
+	ratio := o.AspectRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	height := int(math.Round(float64(srcHeight) * ratio))
+	linear := ratio != 1 && o.Filter == FilterLinear
+	paletted := o.ColorModel == ColorModelPaletted && !linear
+
+	pal := make([]color.RGBA, len(o.Palette))
+	copy(pal, o.Palette)
+	opaque := true
+	for _, c := range pal {
+		opaque = opaque && c.A == 255
+	}
+	for _, p := range ansiImage.Pix {
+		opaque = opaque && (p.ForegroundRGB.A == 0 || p.ForegroundRGB.A == 255) && (p.BackgroundRGB.A == 0 || p.BackgroundRGB.A == 255)
+	}
+
+	pw := &pngWriter{w: w}
+	pw.write(pngSignature)
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:8], uint32(height))
+	ihdr[8] = 8 // bit depth
+	bpp := 4
+	switch {
+	case paletted:
+		ihdr[9] = 3
+		bpp = 1
+	case opaque:
+		ihdr[9] = 2
+		bpp = 3
+	default:
+		ihdr[9] = 6
+	}
+	pw.writeChunk("IHDR", ihdr)
+	if paletted {
+		plte := make([]byte, 0, 3*len(pal))
+		trns := make([]byte, 0, len(pal))
+		for _, c := range pal {
+			plte = append(plte, c.R, c.G, c.B)
+			trns = append(trns, c.A)
+		}
+		pw.writeChunk("PLTE", plte)
+		if !opaque {
+			pw.writeChunk("tRNS", trns)
+		}
+	}
+
+	idat := &idatWriter{pw: pw}
+	zw := zlib.NewWriter(idat)
+	line := make([]byte, 1+width*bpp)
+	writeRow := func(row []byte) error {
+		// Filter type 0 (none)
+		switch bpp {
+		case 1:
+			copy(line[1:], row)
+		case 3:
+			for x := 0; x < width; x++ {
+				copy(line[1+x*3:4+x*3], row[x*4:x*4+3])
+			}
+		default:
+			for x := 0; x < width; x++ {
+				p := line[1+x*4 : 5+x*4]
+				copy(p, row[x*4:x*4+4])
+				// PNG uses non-premultiplied alpha
+				if a := int(p[3]); a != 0 && a != 255 {
+					p[0] = byte(int(p[0]) * 255 / a)
+					p[1] = byte(int(p[1]) * 255 / a)
+					p[2] = byte(int(p[2]) * 255 / a)
+				}
+			}
+		}
+		_, err := zw.Write(line)
+		return err
+	}
+
+	var next, k int // next output row and current source row
+	cur := make([]byte, width*4)
+	prev := make([]byte, width*4)
+	out := make([]byte, width*4)
+	err := RenderBands(ansiImage, &o, bandOpts, func(band draw.Image) error {
+		for y := 0; y < band.Bounds().Dy(); y, k = y+1, k+1 {
+			var row []byte
+			switch b := band.(type) {
+			case *image.Paletted:
+				row = b.Pix[y*b.Stride : y*b.Stride+width]
+				if linear {
+					for x, c := range row {
+						p := pal[c]
+						cur[x*4+0] = p.R
+						cur[x*4+1] = p.G
+						cur[x*4+2] = p.B
+						cur[x*4+3] = p.A
+					}
+					row = cur
+				}
+			case *image.RGBA:
+				row = b.Pix[y*b.Stride : y*b.Stride+width*4]
+			}
+			if !linear {
+				for next < height && nearestSourceRow(next, ratio, srcHeight) == k {
+					if err := writeRow(row); err != nil {
+						return err
+					}
+					next++
+				}
+				continue
+			}
+			copy(cur, row)
+			for next < height {
+				y0, y1, w := linearSourceRows(next, ratio, srcHeight)
+				if y1 > k {
+					break
+				}
+				r0, r1 := prev, cur
+				if y0 == k {
+					r0 = cur
+				}
+				if y1 < k {
+					r1 = prev
+				}
+				lerpRow(out, r0, r1, w)
+				if err := writeRow(out); err != nil {
+					return err
+				}
+				next++
+			}
+			prev, cur = cur, prev
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	idat.flush()
+	pw.writeChunk("IEND", nil)
+	return pw.err
+}
+
+// idatWriter splits the compressed image data into IDAT chunks.
+type idatWriter struct {
+	pw  *pngWriter
+	buf []byte
+}
+
+const maxIDATSize = 1 << 16
+
+func (w *idatWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= maxIDATSize {
+		n := len(w.buf) / maxIDATSize * maxIDATSize
+		for i := 0; i < n; i += maxIDATSize {
+			w.pw.writeChunk("IDAT", w.buf[i:i+maxIDATSize])
+		}
+		w.buf = append(w.buf[:0], w.buf[n:]...)
+	}
+	return len(b), w.pw.err
+}
+
+func (w *idatWriter) flush() {
+	if len(w.buf) != 0 {
+		w.pw.writeChunk("IDAT", w.buf)
+		w.buf = w.buf[:0]
+	}
+}
@@ -0,0 +1,82 @@
+package ansi
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func TestRenderBands(t *testing.T) {
+	ansiImage := &Image{Width: 3, Height: 10, Pix: make([]Pixel, 3*10)}
+	var y int
+	err := RenderBands(ansiImage, nil, &BandOptions{Rows: 4, Workers: 2}, func(band draw.Image) error {
+		if b := band.Bounds(); b.Min.Y != y || b.Dx() != 24 {
+			t.Errorf("Unexpected band bounds %v at %d", b, y)
+		}
+		y = band.Bounds().Max.Y
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y != 160 {
+		t.Errorf("Expected bands to cover 160 rows, got %d", y)
+	}
+}
+
+func TestEncodePNG(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	ansiImage := &Image{Width: 20, Height: 7, Pix: make([]Pixel, 20*7)}
+	for i := range ansiImage.Pix {
+		ansiImage.Pix[i] = Pixel{C: byte(rnd.Intn(256)), ForegroundColor: byte(rnd.Intn(16)), BackgroundColor: byte(rnd.Intn(16))}
+	}
+	rgbImage := &Image{Width: 20, Height: 7, Pix: append([]Pixel(nil), ansiImage.Pix...)}
+	rgbImage.Pix[3].ForegroundRGB = color.RGBA{1, 2, 3, 255}
+
+	tests := []struct {
+		img  *Image
+		opts *RenderOptions
+	}{
+		{ansiImage, nil},
+		{ansiImage, &RenderOptions{AspectRatio: 1.35, LetterSpacing9: true}},
+		{ansiImage, &RenderOptions{AspectRatio: 1.2, Filter: FilterLinear}},
+		{ansiImage, &RenderOptions{AspectRatio: 0.7, Filter: FilterLinear}},
+		{rgbImage, &RenderOptions{AspectRatio: 1.2}},
+		{rgbImage, &RenderOptions{ColorModel: ColorModelPaletted}},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		if err := EncodePNG(&buf, test.img, test.opts, &BandOptions{Rows: 2, Workers: 3}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		want := Render(test.img, test.opts)
+		if got.Bounds() != want.Bounds() {
+			t.Fatalf("%d: expected bounds %v, got %v", i, want.Bounds(), got.Bounds())
+		}
+		if !samePixels(got, want) {
+			t.Errorf("%d: pixels differ from Render", i)
+		}
+	}
+}
+
+func samePixels(a, b image.Image) bool {
+	r := a.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			r0, g0, b0, a0 := a.At(x, y).RGBA()
+			r1, g1, b1, a1 := b.At(x, y).RGBA()
+			if r0 != r1 || g0 != g1 || b0 != b1 || a0 != a1 {
+				return false
+			}
+		}
+	}
+	return true
+}
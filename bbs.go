@@ -0,0 +1,159 @@
+package ansi
+
+import (
+	"io"
+)
+
+// BBSCodes selects the BBS color codes recognized by a parser in addition
+// to ANSI escape sequences.
+type BBSCodes byte
+
+const (
+	// BBSCodesPCBoard recognizes PCBoard @X codes such as @X1F where the
+	// two hex digits are a VGA text mode attribute.
+	BBSCodesPCBoard BBSCodes = 1 << iota
+	// BBSCodesWildcat recognizes Wildcat codes such as @1F@ where the two
+	// hex digits are a VGA text mode attribute.
+	BBSCodesWildcat
+	// BBSCodesPipe recognizes Renegade and Mystic pipe codes such as |07.
+	// |00 to |15 set the text color, |16 to |23 the background color, and
+	// |24 to |31 a high intensity background color.
+	BBSCodesPipe
+
+	BBSCodesAll = BBSCodesPCBoard | BBSCodesWildcat | BBSCodesPipe
+)
+
+// NewBBSParser returns a parser that turns the selected BBS color codes
+// into the equivalent SelectGraphicsRendition sequences. Anything that
+// isn't a complete code is parsed as text. The high bit of an attribute is
+// blink as it is for a DOS console.
+func NewBBSParser(r io.ByteReader, codes BBSCodes) *Parser {
+	return &Parser{r: r, bbs: codes}
+}
+
+// parseBBSCode parses the code starting with the current byte ('@' or '|')
+// and appends its sequences to seq. If there's no code the byte is
+// appended as a character and any bytes read ahead are parsed again.
+func (p *Parser) parseBBSCode(seq []Sequence) []Sequence {
+	c := p.b
+	la := &lookahead{p: p, ok: true}
+	var s []Sequence
+	switch {
+	case c == '|' && p.bbs&BBSCodesPipe != 0:
+		d1 := la.next(isDecimal)
+		d2 := la.next(isDecimal)
+		if la.ok {
+			s = pipeColor(int(d1-'0')*10 + int(d2-'0'))
+		}
+	case c == '@' && p.bbs&(BBSCodesPCBoard|BBSCodesWildcat) != 0:
+		pcboard := p.bbs&BBSCodesPCBoard != 0
+		wildcat := p.bbs&BBSCodesWildcat != 0
+		b := la.next(func(b byte) bool {
+			return pcboard && b == 'X' || wildcat && isHex(b)
+		})
+		switch {
+		case !la.ok:
+		case b == 'X':
+			h1 := la.next(isHex)
+			h2 := la.next(isHex)
+			if la.ok {
+				s = attrColor(hexValue(h1)<<4 | hexValue(h2))
+			}
+		default:
+			h2 := la.next(isHex)
+			la.next(func(b byte) bool { return b == '@' })
+			if la.ok {
+				s = attrColor(hexValue(b)<<4 | hexValue(h2))
+			}
+		}
+	}
+	if s != nil {
+		return append(seq, s...)
+	}
+	la.unread()
+	return append(seq, Character{C: c})
+}
+
+// lookahead reads the bytes of a possible code until one doesn't match.
+type lookahead struct {
+	p   *Parser
+	buf []byte // bytes read
+	ok  bool   // all bytes so far matched
+}
+
+// next reads a byte and returns it. After the end of the input or a byte
+// that doesn't match valid, ok is false and nothing more is read.
+func (l *lookahead) next(valid func(byte) bool) byte {
+	if !l.ok {
+		return 0
+	}
+	b, err := l.p.readByte()
+	if err == io.EOF {
+		l.ok = false
+		return 0
+	} else if err != nil {
+		panic(err)
+	}
+	l.buf = append(l.buf, b)
+	l.ok = valid(b)
+	return b
+}
+
+// unread returns the bytes read so they're parsed again.
+func (l *lookahead) unread() {
+	l.p.unread = append(l.buf, l.p.unread...)
+	l.p.offset -= int64(len(l.buf))
+}
+
+// attrColor returns the sequences that select the colors of a VGA text
+// mode attribute.
+func attrColor(attr byte) []Sequence {
+	s := []Sequence{SelectGraphicsRendition{N: GraphicsRenditionReset}}
+	if attr&0x08 != 0 {
+		s = append(s, SelectGraphicsRendition{N: GraphicsRenditionBold})
+	}
+	if attr&0x80 != 0 {
+		s = append(s, SelectGraphicsRendition{N: GraphicRenditionBlinkSlow})
+	}
+	return append(s,
+		SelectGraphicsRendition{N: GraphicsRenditionSetTextColor0 + GraphicsRendition(vgaToANSI[attr&7])},
+		SelectGraphicsRendition{N: GraphicsRenditionSetBackgroundColor0 + GraphicsRendition(vgaToANSI[attr>>4&7])},
+	)
+}
+
+// pipeColor returns the sequences for pipe code n or nil if it isn't a color.
+func pipeColor(n int) []Sequence {
+	switch {
+	case n < 8:
+		// The default text color clears bold without touching the background
+		return []Sequence{
+			SelectGraphicsRendition{N: GraphicsrenditionDefaultTextColor},
+			SelectGraphicsRendition{N: GraphicsRenditionSetTextColor0 + GraphicsRendition(vgaToANSI[n])},
+		}
+	case n < 16:
+		return []Sequence{SelectGraphicsRendition{N: GraphicsRenditionSetBrightTextColor0 + GraphicsRendition(vgaToANSI[n-8])}}
+	case n < 24:
+		return []Sequence{SelectGraphicsRendition{N: GraphicsRenditionSetBackgroundColor0 + GraphicsRendition(vgaToANSI[n-16])}}
+	case n < 32:
+		return []Sequence{SelectGraphicsRendition{N: GraphicsRenditionSetBrightBackgroundColor0 + GraphicsRendition(vgaToANSI[n-24])}}
+	}
+	return nil
+}
+
+func isDecimal(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isHex(b byte) bool {
+	return b >= '0' && b <= '9' || b >= 'a' && b <= 'f' || b >= 'A' && b <= 'F'
+}
+
+func hexValue(b byte) byte {
+	switch {
+	case b >= 'a':
+		return b - 'a' + 10
+	case b >= 'A':
+		return b - 'A' + 10
+	}
+	return b - '0'
+}
@@ -0,0 +1,53 @@
+package ansi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBBSParser(t *testing.T) {
+	cases := []struct {
+		bbs  string
+		ansi string
+	}{
+		{"@X1FHi@X07!", "\x1b[0;1;37;44mHi\x1b[0;37;40m!"},
+		{"@1F@Hi@07@!", "\x1b[0;1;37;44mHi\x1b[0;37;40m!"},
+		{"@X9cA", "\x1b[0;1;5;31;44mA"},
+		{"|14|17A|07B|24C", "\x1b[93;44mA\x1b[39;37mB\x1b[100mC"},
+		// Incomplete codes are text
+		{"@X1|1@1F@@XZZ|9", "@X1|1\x1b[0;1;37;44m@XZZ|9"},
+		{"a@b|c@X1", "a@b|c@X1"},
+	}
+	for _, c := range cases {
+		seq, err := NewBBSParser(bytes.NewReader([]byte(c.bbs)), BBSCodesAll).ParseAll()
+		if err != nil {
+			t.Fatalf("%q: %s", c.bbs, err)
+		}
+		got, err := RenderSequence(seq)
+		if err != nil {
+			t.Fatalf("%q: %s", c.bbs, err)
+		}
+		want, err := Parse(bytes.NewReader([]byte(c.ansi)))
+		if err != nil {
+			t.Fatalf("%q: %s", c.ansi, err)
+		}
+		if !reflect.DeepEqual(got.Pix, want.Pix) {
+			t.Errorf("%q rendered\n%+v\nexpected\n%+v", c.bbs, got.Pix, want.Pix)
+		}
+	}
+}
+
+func TestBBSParserCodes(t *testing.T) {
+	p := NewBBSParser(bytes.NewReader([]byte("@X1F|07")), BBSCodesPipe)
+	seq, err := p.ParseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seq) != 6 {
+		t.Fatalf("Expected @X1F as text and 2 sequences for |07, got %+v", seq)
+	}
+	if p.Offset() != 7 {
+		t.Errorf("Expected offset 7, got %d", p.Offset())
+	}
+}
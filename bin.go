@@ -0,0 +1,77 @@
+package ansi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// defaultBINWidth is the width of a BIN file without a SAUCE record.
+const defaultBINWidth = 160
+
+// ReadBIN reads a BIN file: raw VGA text memory with a character and
+// attribute byte for each cell. If width is 0 it's taken from the SAUCE
+// record, or 160 if there isn't one. The SAUCE non-blink flag selects iCE
+// colors where the high bit of the attribute is a high intensity
+// background rather than blink. A partial last row is padded with zero
+// pixels.
+func ReadBIN(r io.Reader, width int) (*Image, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b, sauce := splitSauce(b)
+	ice := false
+	if sauce != nil {
+		ice = sauce.Flags&SauceFlagNonBlink != 0
+		if width <= 0 && sauce.DataType == SauceDataTypeBinaryText && sauce.FileType != 0 {
+			width = int(sauce.FileType) * 2
+		}
+	}
+	if width <= 0 {
+		width = defaultBINWidth
+	}
+	return readAttrPairs(b, width, ice), nil
+}
+
+// WriteBIN writes img as a BIN file followed by a SAUCE record giving its
+// width. The SAUCE non-blink flag is set if any pixel has a high intensity
+// background, in which case blinking isn't available. SAUCE stores half
+// of the width so an odd width is padded with a column of zero pixels.
+func WriteBIN(w io.Writer, img *Image) error {
+	width := img.Width + img.Width%2
+	if width > 510 {
+		return fmt.Errorf("BIN width must be at most 510, got %d", img.Width)
+	}
+	ice := img.needsICEColors()
+	var buf bytes.Buffer
+	for i, p := range img.Pix {
+		attr, err := pixelAttr(p, ice)
+		if err != nil {
+			return fmt.Errorf("row %d column %d: %w", i/img.Width+1, i%img.Width+1, err)
+		}
+		buf.WriteByte(p.C)
+		buf.WriteByte(attr)
+		if width != img.Width && i%img.Width == img.Width-1 {
+			buf.WriteByte(0)
+			buf.WriteByte(0)
+		}
+	}
+	s := &Sauce{
+		FileSize: uint32(buf.Len()),
+		DataType: SauceDataTypeBinaryText,
+		FileType: byte(width / 2),
+	}
+	if ice {
+		s.Flags |= SauceFlagNonBlink
+	}
+	b, err := s.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	buf.WriteByte(eof)
+	buf.Write(b)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
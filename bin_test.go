@@ -0,0 +1,84 @@
+package ansi
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBINRoundTrip(t *testing.T) {
+	src := "\x1b[1;33;44mHello\x1b[0;5;31mworld\r\n\x1b[0;36mline two"
+	want, err := Parse(bufio.NewReader(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteBIN(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadBIN(&buf, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Width != want.Width || !reflect.DeepEqual(got.Pix, want.Pix) {
+		t.Errorf("Image does not match after round trip")
+	}
+}
+
+func TestWriteBINOddWidth(t *testing.T) {
+	want, err := Parse(bufio.NewReader(strings.NewReader("\x1b[32mabc\r\nde")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteBIN(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadBIN(&buf, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Width != 4 || got.Height != 2 {
+		t.Fatalf("Expected 4x2 with a padding column, got %dx%d", got.Width, got.Height)
+	}
+	for y := 0; y < 2; y++ {
+		if !reflect.DeepEqual(got.Pix[y*4:y*4+3], want.Pix[y*3:y*3+3]) {
+			t.Errorf("Row %d does not match after round trip", y)
+		}
+		if p := got.Pix[y*4+3]; p != (Pixel{}) {
+			t.Errorf("Expected a zero pixel in the padding column, got %+v", p)
+		}
+	}
+}
+
+func TestReadBINICEColors(t *testing.T) {
+	img := &Image{Width: 2, Height: 1, Pix: []Pixel{
+		{C: 'A', ForegroundColor: 7, BackgroundColor: 12},
+		{C: 'B', ForegroundColor: 0, BackgroundColor: 7},
+	}}
+	var buf bytes.Buffer
+	if err := WriteBIN(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if b := buf.Bytes(); !bytes.Equal(b[:4], []byte{'A', 0x97, 'B', 0x70}) {
+		t.Errorf("Unexpected attributes % x", b[:4])
+	}
+	got, err := ReadBIN(bytes.NewReader(buf.Bytes()), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Pix, img.Pix) {
+		t.Errorf("Expected %+v, got %+v", img.Pix, got.Pix)
+	}
+
+	// Without SAUCE the high bit is blink
+	got, err = ReadBIN(bytes.NewReader(buf.Bytes()[:4]), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := got.Pix[0]; p.BackgroundColor != 4 || p.Blink != BlinkSlow {
+		t.Errorf("Expected blink on blue, got %+v", p)
+	}
+}
@@ -0,0 +1,174 @@
+package ansi
+
+import (
+	"image"
+	"sync"
+
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// codePageFont returns base with its glyphs arranged for cs. Characters
+// that are the same as in code page 437 keep their glyph and the others
+// use the glyph of base that displays the same rune. Characters that code
+// page 437 doesn't have are rasterized from Go Mono, and are blank if Go
+// Mono doesn't have them either.
+func codePageFont(base *Font, cs *Charset) *Font {
+	n := base.stride() * base.Height
+	f := &Font{Width: base.Width, Height: base.Height, Bitmap: make([]byte, 256*n)}
+	for c, r := range cs {
+		var g []byte
+		if b, ok := pcASCIIFromUnicode[r]; r == CharsetCP437[c] {
+			g = base.glyph(c)
+		} else if ok {
+			g = base.glyph(int(b))
+		} else {
+			g = goMonoGlyph(r, base)
+		}
+		copy(f.Bitmap[c*n:(c+1)*n], g)
+	}
+	return f
+}
+
+var (
+	goMonoOnce sync.Once
+	goMonoFont *sfnt.Font
+	goMonoMu   sync.Mutex
+	goMonoBuf  sfnt.Buffer
+)
+
+// goMonoGlyph returns the glyph for r rasterized from Go Mono to match
+// base, or nil if Go Mono doesn't have r. Go Mono is scaled so that its
+// 'H' covers the same pixels as the 'H' of base, which lines up the
+// baseline, cap height, and stems.
+func goMonoGlyph(r rune, base *Font) []byte {
+	goMonoOnce.Do(func() {
+		goMonoFont, _ = sfnt.Parse(gomono.TTF)
+	})
+	if goMonoFont == nil {
+		return nil
+	}
+	goMonoMu.Lock()
+	defer goMonoMu.Unlock()
+	h, ok := goMonoSegments('H')
+	if !ok {
+		return nil
+	}
+	segs, ok := goMonoSegments(r)
+	if !ok {
+		return nil
+	}
+	// The pixel bounds of 'H' in base and its bounds in font units. The
+	// outline is made a pixel narrower and then emboldened by a pixel to
+	// match the double width strokes of the VGA fonts.
+	px := glyphBounds(base, base.glyph('H'))
+	px.Max.X--
+	if px.Empty() {
+		return nil
+	}
+	units := segmentBounds(h)
+	sx := float32(px.Dx()) / float32(units.Dx())
+	sy := float32(px.Dy()) / float32(units.Dy())
+	pt := func(p fixed.Point26_6) (float32, float32) {
+		return float32(px.Min.X) + float32(int(p.X)-units.Min.X)*sx, float32(px.Min.Y) + float32(int(p.Y)-units.Min.Y)*sy
+	}
+
+	width, height := base.Width, base.Height
+	z := vector.NewRasterizer(width, height)
+	for _, s := range segs {
+		x0, y0 := pt(s.Args[0])
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			z.MoveTo(x0, y0)
+		case sfnt.SegmentOpLineTo:
+			z.LineTo(x0, y0)
+		case sfnt.SegmentOpQuadTo:
+			x1, y1 := pt(s.Args[1])
+			z.QuadTo(x0, y0, x1, y1)
+		case sfnt.SegmentOpCubeTo:
+			x1, y1 := pt(s.Args[1])
+			x2, y2 := pt(s.Args[2])
+			z.CubeTo(x0, y0, x1, y1, x2, y2)
+		}
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	z.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+
+	stride := base.stride()
+	g := make([]byte, stride*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Pixels partly covered are set to keep the strokes from
+			// breaking up, and so is the pixel right of each
+			if mask.Pix[y*mask.Stride+x] >= 0x60 || x > 0 && mask.Pix[y*mask.Stride+x-1] >= 0x60 {
+				g[y*stride+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return g
+}
+
+// goMonoSegments returns the outline of r in Go Mono in font units.
+func goMonoSegments(r rune) (sfnt.Segments, bool) {
+	f, b := goMonoFont, &goMonoBuf
+	idx, err := f.GlyphIndex(b, r)
+	if err != nil || idx == 0 {
+		return nil, false
+	}
+	// At a ppem of the units per em the coordinates are in font units
+	segs, err := f.LoadGlyph(b, idx, fixed.Int26_6(f.UnitsPerEm())<<6, nil)
+	if err != nil {
+		return nil, false
+	}
+	// The buffer is reused by the next call
+	return append(sfnt.Segments(nil), segs...), true
+}
+
+// segmentBounds returns the bounds of the points of an outline. It's exact
+// for outlines made of lines such as 'H'.
+func segmentBounds(segs sfnt.Segments) image.Rectangle {
+	var r image.Rectangle
+	for i, s := range segs {
+		p := image.Pt(int(s.Args[0].X), int(s.Args[0].Y))
+		if i == 0 {
+			r = image.Rectangle{p, p}
+		}
+		r = r.Union(image.Rectangle{p, p.Add(image.Pt(1, 1))})
+	}
+	return r
+}
+
+// glyphBounds returns the bounds of the set pixels of a glyph of f.
+func glyphBounds(f *Font, glyph []byte) image.Rectangle {
+	var r image.Rectangle
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			if f.set(glyph, x, y) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+// vga19 returns the 8x19 font of the 640x480 VGA graphics mode derived
+// from the 8x16 font. The glyphs start on the second row. The line
+// graphics characters repeat their top and bottom rows so that they still
+// join up.
+func vga19() *Font {
+	const height = 19
+	f := &Font{Width: 8, Height: height, Bitmap: make([]byte, 256*height)}
+	for c := 0; c < 256; c++ {
+		src := FontVGA16.glyph(c)
+		dst := f.Bitmap[c*height : (c+1)*height]
+		copy(dst[1:], src)
+		if c >= 0xb0 && c <= 0xdf {
+			dst[0] = src[0]
+			dst[17] = src[15]
+			dst[18] = src[15]
+		}
+	}
+	return f
+}
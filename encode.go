@@ -0,0 +1,194 @@
+package ansi
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// EncodeOptions are the options for EncodeImage.
+type EncodeOptions struct {
+	// Sauce, if not nil, is appended to the output after an EOF marker.
+	// FileSize, DataType, FileType, TInfo1 (width), and TInfo2 (height)
+	// are filled in when zero.
+	Sauce *Sauce
+	// BlankSpaces treats spaces on a black background as unwritten cells
+	// so that they can be skipped with cursor forward. The result renders
+	// the same but the decoded Pix has zero pixels in place of the spaces.
+	BlankSpaces bool
+}
+
+// sgrState is the graphics rendition state of the renderer as tracked by the encoder.
+type sgrState struct {
+	fg    byte // 0-7, intensity is tracked by bold
+	bold  bool
+	bg    byte // 0-15
+	blink Blink
+	fgRGB color.RGBA
+	bgRGB color.RGBA
+}
+
+var defaultSGRState = sgrState{fg: 7}
+
+// EncodeImage writes img as an ANSI file that reproduces it when parsed.
+// Zero pixels (those never written by the renderer) are skipped using cursor
+// forward and graphics rendition changes are merged into a single escape
+// sequence with the fewest parameters. Trailing blank cells of a row and
+// rows that are entirely blank at the end of the image are not preserved.
+func EncodeImage(w io.Writer, img *Image, opts *EncodeOptions) error {
+	if opts == nil {
+		opts = &EncodeOptions{}
+	}
+	if img.Width > defaultScreenWidth {
+		return fmt.Errorf("image width %d is larger than the screen width %d", img.Width, defaultScreenWidth)
+	}
+
+	isBlank := func(p Pixel) bool {
+		return p == Pixel{} || (opts.BlankSpaces && p.C == ' ' && p.BackgroundColor == 0 && p.BackgroundRGB.A == 0 && p.Blink == BlinkNone)
+	}
+
+	var buf bytes.Buffer
+	state := defaultSGRState
+	var params []int
+	wrapped := false
+	for y := 0; y < img.Height; y++ {
+		row := img.Pix[y*img.Width : (y+1)*img.Width]
+		end := len(row)
+		for end > 0 && isBlank(row[end-1]) {
+			end--
+		}
+		if y != 0 && !wrapped {
+			buf.WriteString("\r\n")
+		}
+		skip := 0
+		for x, p := range row[:end] {
+			if isBlank(p) {
+				skip++
+				continue
+			}
+			if skip != 0 {
+				buf.WriteString("\x1b[")
+				if skip != 1 {
+					buf.WriteString(strconv.Itoa(skip))
+				}
+				buf.WriteByte('C')
+				skip = 0
+			}
+			switch p.C {
+			case lf, cr, eof, esc:
+				return fmt.Errorf("cannot encode character 0x%02x at row %d column %d", p.C, y+1, x+1)
+			}
+			want := sgrState{
+				fg:    p.ForegroundColor & 7,
+				bold:  p.ForegroundColor > 7,
+				bg:    p.BackgroundColor & 15,
+				blink: p.Blink,
+				fgRGB: p.ForegroundRGB,
+				bgRGB: p.BackgroundRGB,
+			}
+			// The color index of a 24-bit color is the closest VGA color
+			// so the indexed state is left as it is
+			if want.fgRGB.A != 0 {
+				want.fg, want.bold = state.fg, state.bold
+			}
+			if want.bgRGB.A != 0 {
+				want.bg = state.bg
+			}
+			if want != state {
+				params = state.transition(params[:0], want)
+				writeSGR(&buf, params)
+				state = want
+			}
+			buf.WriteByte(p.C)
+		}
+		wrapped = end == defaultScreenWidth
+	}
+	if state != defaultSGRState {
+		writeSGR(&buf, []int{int(GraphicsRenditionReset)})
+	}
+
+	if opts.Sauce != nil {
+		s := *opts.Sauce
+		if s.FileSize == 0 {
+			s.FileSize = uint32(buf.Len())
+		}
+		if s.DataType == SauceDataTypeNone {
+			s.DataType = SauceDataTypeCharacter
+			s.FileType = SauceFileTypeANSi
+		}
+		if s.TInfo1 == 0 {
+			s.TInfo1 = uint16(img.Width)
+		}
+		if s.TInfo2 == 0 {
+			s.TInfo2 = uint16(img.Height)
+		}
+		b, err := s.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(eof)
+		buf.Write(b)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// transition appends the graphics rendition parameters that change the
+// state from s to want.
+func (s sgrState) transition(params []int, want sgrState) []int {
+	// Bold and blink can only be turned off with a reset
+	if (s.bold && !want.bold) || (s.blink != BlinkNone && want.blink == BlinkNone) {
+		params = append(params, int(GraphicsRenditionReset))
+		s = defaultSGRState
+	}
+	if want.fgRGB.A != 0 && want.fgRGB != s.fgRGB {
+		params = appendRGB(params, 38, want.fgRGB)
+	}
+	if want.bold && !s.bold {
+		params = append(params, int(GraphicsRenditionBold))
+	}
+	if want.blink != s.blink {
+		switch want.blink {
+		case BlinkSlow:
+			params = append(params, int(GraphicRenditionBlinkSlow))
+		case BlinkFast:
+			params = append(params, int(GraphicRenditionBlinkFast))
+		}
+	}
+	if want.fgRGB.A == 0 && (want.fg != s.fg || s.fgRGB.A != 0) {
+		params = append(params, int(GraphicsRenditionSetTextColor0)+int(want.fg))
+	}
+	switch {
+	case want.bgRGB.A != 0:
+		if want.bgRGB != s.bgRGB {
+			params = appendRGB(params, 48, want.bgRGB)
+		}
+	case want.bg != s.bg || s.bgRGB.A != 0:
+		if want.bg > 7 {
+			params = append(params, int(GraphicsRenditionSetBrightBackgroundColor0)+int(want.bg-8))
+		} else {
+			params = append(params, int(GraphicsRenditionSetBackgroundColor0)+int(want.bg))
+		}
+	}
+	return params
+}
+
+// appendRGB appends the parameters for a 24-bit color where n is 38 for
+// the text color or 48 for the background.
+func appendRGB(params []int, n int, c color.RGBA) []int {
+	return append(params, n, 2, int(c.R), int(c.G), int(c.B))
+}
+
+func writeSGR(buf *bytes.Buffer, params []int) {
+	buf.WriteString("\x1b[")
+	for i, n := range params {
+		if i != 0 {
+			buf.WriteByte(';')
+		}
+		buf.WriteString(strconv.Itoa(n))
+	}
+	buf.WriteByte('m')
+}
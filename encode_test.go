@@ -0,0 +1,96 @@
+package ansi
+
+import (
+	"bufio"
+	"bytes"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeImageRoundTrip(t *testing.T) {
+	src := "\x1b[0m\x1b[1;33mHello\x1b[0;44m  \x1b[5;31mworld\x1b[0m\r\n" +
+		"\x1b[10C\x1b[102;30mX\x1b[0m\r\n" +
+		"\r\n" +
+		strings.Repeat("\xdb", 80) +
+		"\x1b[1;37;41mend"
+	want, err := Parse(bufio.NewReader(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeImage(&buf, want, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(src) {
+		t.Errorf("Encoded size %d not smaller than source size %d", buf.Len(), len(src))
+	}
+	got, err := Parse(bufio.NewReader(bytes.NewReader(buf.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Width != want.Width || got.Height != want.Height {
+		t.Fatalf("Size %dx%d, want %dx%d", got.Width, got.Height, want.Width, want.Height)
+	}
+	if !reflect.DeepEqual(got.Pix, want.Pix) {
+		t.Errorf("Pix does not match after round trip:\n%q", buf.String())
+	}
+}
+
+func TestEncodeImageTrueColor(t *testing.T) {
+	src := "\x1b[38;2;255;128;0mA\x1b[1mB\x1b[48;5;196mC\x1b[38;5;9mD\x1b[0;48;2;0;0;1mE\x1b[44mF\x1b[0;38;2;255;255;255mG\x1b[31mH\x1b[0;48;2;9;9;9mI\x1b[49mJ\x1b[105mK\x1b[49mL\x1b[0m"
+	want, err := Parse(bufio.NewReader(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Pix[0].ForegroundRGB != (color.RGBA{255, 128, 0, 255}) {
+		t.Fatalf("Expected 24-bit foreground, got %v", want.Pix[0].ForegroundRGB)
+	}
+	if want.Pix[3].ForegroundRGB.A != 0 || want.Pix[3].ForegroundColor != 9 {
+		t.Fatalf("Expected 38;5;9 to be bright red, got %+v", want.Pix[3])
+	}
+	if want.Pix[6].ForegroundColor != 15 || want.Pix[7].ForegroundColor != 1 {
+		t.Fatalf("Expected white then red, got %+v and %+v", want.Pix[6], want.Pix[7])
+	}
+	for _, i := range []int{9, 11} {
+		if p := want.Pix[i]; p.BackgroundColor != 0 || p.BackgroundRGB.A != 0 {
+			t.Fatalf("Expected 49 to reset the background, got %+v", p)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeImage(&buf, want, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(bufio.NewReader(bytes.NewReader(buf.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Pix, want.Pix) {
+		t.Errorf("Pix does not match after round trip:\n%q", buf.String())
+	}
+}
+
+func TestEncodeImageSauce(t *testing.T) {
+	img := &Image{Width: 1, Height: 1, Pix: []Pixel{{C: 'A', ForegroundColor: 7}}}
+	var buf bytes.Buffer
+	if err := EncodeImage(&buf, img, &EncodeOptions{Sauce: &Sauce{Title: "Test", Comments: []string{"comment"}}}); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if exp := 1 + 1 + 5 + sauceCommentSize + sauceRecordSize; len(b) != exp {
+		t.Fatalf("Expected %d bytes, got %d", exp, len(b))
+	}
+	if b[0] != 'A' || b[1] != eof {
+		t.Errorf("Expected data followed by EOF, got %q", b[:2])
+	}
+	rec := b[len(b)-sauceRecordSize:]
+	if !bytes.HasPrefix(rec, []byte("SAUCE00Test ")) {
+		t.Errorf("Bad SAUCE record %q", rec)
+	}
+	if rec[90] != 1 || rec[94] != byte(SauceDataTypeCharacter) || rec[95] != SauceFileTypeANSi {
+		t.Errorf("Bad SAUCE record fields %q", rec[90:98])
+	}
+}
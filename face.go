@@ -0,0 +1,184 @@
+package ansi
+
+import (
+	"image"
+	"image/color"
+	"sort"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Face returns the font as a font.Face so that it can be used anywhere a
+// vector font can, including RenderOptions.Face. The glyph for each
+// character is mapped to the rune given by cs, or CharsetCP437 if cs is
+// nil, except that control characters cs leaves unmapped use the runes of
+// the glyphs they display as in text mode such as '☺'. When a rune appears
+// more than once the first character is used.
+func (f *Font) Face(cs *Charset) font.Face {
+	if cs == nil {
+		cs = CharsetCP437
+	}
+	type entry struct {
+		r rune
+		c int
+	}
+	seen := make(map[rune]bool)
+	var entries []entry
+	for c := range cs {
+		r := charsetRune(cs, byte(c))
+		if c < f.NumGlyphs() && !seen[r] {
+			seen[r] = true
+			entries = append(entries, entry{r, c})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].r < entries[j].r })
+
+	// The glyphs are laid out in rune order so that runs of consecutive
+	// runes become a single range.
+	mask := image.NewAlpha(image.Rect(0, 0, f.Width, f.Height*len(entries)))
+	var ranges []basicfont.Range
+	for i, e := range entries {
+		g := f.glyph(e.c)
+		for y := 0; y < f.Height; y++ {
+			for x := 0; x < f.Width; x++ {
+				if f.set(g, x, y) {
+					mask.Pix[(i*f.Height+y)*mask.Stride+x] = 0xff
+				}
+			}
+		}
+		if n := len(ranges); n != 0 && ranges[n-1].High == e.r {
+			ranges[n-1].High++
+		} else {
+			ranges = append(ranges, basicfont.Range{Low: e.r, High: e.r + 1, Offset: i})
+		}
+	}
+	descent := f.Height / 4
+	return &basicfont.Face{
+		Advance: f.Width,
+		Width:   f.Width,
+		Height:  f.Height,
+		Ascent:  f.Height - descent,
+		Descent: descent,
+		Mask:    mask,
+		Ranges:  ranges,
+	}
+}
+
+// charsetRune returns the rune cs maps c to. Control characters that cs
+// maps to themselves are drawn as the glyphs they display as in text mode
+// since faces don't have glyphs for control codes.
+func charsetRune(cs *Charset, c byte) rune {
+	if r := cs[c]; r != rune(c) || c >= 32 && c != 127 {
+		return r
+	}
+	return pcASCIIRune(c)
+}
+
+// faceMu serializes use of faces since they generally aren't safe for
+// concurrent use and RenderBands draws from several goroutines.
+var faceMu sync.Mutex
+
+// faceCells draws characters using a font.Face. The glyph coverage for
+// each rune is cached as a cell sized alpha mask.
+type faceCells struct {
+	face    font.Face
+	charset *Charset
+	size    image.Point
+	ascent  fixed.Int26_6
+	cache   map[rune][]byte
+}
+
+// faceCellSize returns the size of a cell for face: the advance of 'M' by
+// the line height.
+func faceCellSize(face font.Face) image.Point {
+	faceMu.Lock()
+	defer faceMu.Unlock()
+	m := face.Metrics()
+	adv, ok := face.GlyphAdvance('M')
+	if !ok || adv <= 0 {
+		adv = m.Height / 2
+	}
+	return image.Pt(adv.Ceil(), m.Height.Ceil())
+}
+
+func newFaceCells(o *RenderOptions) *faceCells {
+	faceMu.Lock()
+	ascent := o.Face.Metrics().Ascent
+	faceMu.Unlock()
+	return &faceCells{
+		face:    o.Face,
+		charset: o.Charset,
+		size:    o.cellSize(),
+		ascent:  ascent,
+		cache:   make(map[rune][]byte),
+	}
+}
+
+// pixelRune returns the rune to draw for p.
+func (f *faceCells) pixelRune(p Pixel) rune {
+	if p.R != 0 {
+		return p.R
+	}
+	return charsetRune(f.charset, p.C)
+}
+
+// coverage returns the alpha of each pixel of the cell for r, or nil if
+// the face has no glyph for it.
+func (f *faceCells) coverage(r rune) []byte {
+	if cov, ok := f.cache[r]; ok {
+		return cov
+	}
+	faceMu.Lock()
+	defer faceMu.Unlock()
+	dr, mask, maskp, _, ok := f.face.Glyph(fixed.Point26_6{Y: f.ascent}, r)
+	var cov []byte
+	if ok && mask != nil {
+		cov = make([]byte, f.size.X*f.size.Y)
+		clip := dr.Intersect(image.Rectangle{Max: f.size})
+		for y := clip.Min.Y; y < clip.Max.Y; y++ {
+			for x := clip.Min.X; x < clip.Max.X; x++ {
+				a := mask.At(maskp.X+x-dr.Min.X, maskp.Y+y-dr.Min.Y)
+				cov[y*f.size.X+x] = color.AlphaModel.Convert(a).(color.Alpha).A
+			}
+		}
+	}
+	f.cache[r] = cov
+	return cov
+}
+
+// drawCell draws the cell for p at cell clipped to clip. Partially covered
+// pixels are blended unless the output is paletted.
+func (f *faceCells) drawCell(set pixelSetter, cell, clip image.Rectangle, p Pixel, pal Palette, paletted bool) {
+	cov := f.coverage(f.pixelRune(p))
+	fg := p.foreground(pal)
+	bg := p.background(pal)
+	r := cell.Intersect(clip)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var a byte
+			if cov != nil {
+				a = cov[(y-cell.Min.Y)*f.size.X+x-cell.Min.X]
+			}
+			switch {
+			case a == 0 || paletted && a < 0x80:
+				set(x, y, p.BackgroundColor, p.BackgroundRGB)
+			case a == 0xff || paletted:
+				set(x, y, p.ForegroundColor, p.ForegroundRGB)
+			default:
+				set(x, y, 0, color.RGBA{
+					R: blend8(fg.R, bg.R, a),
+					G: blend8(fg.G, bg.G, a),
+					B: blend8(fg.B, bg.B, a),
+					A: blend8(fg.A, bg.A, a),
+				})
+			}
+		}
+	}
+}
+
+func blend8(fg, bg, a byte) byte {
+	return byte((int(fg)*int(a) + int(bg)*(255-int(a)) + 127) / 255)
+}
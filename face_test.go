@@ -0,0 +1,88 @@
+package ansi
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+)
+
+func TestFontFace(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	ansiImage := &Image{Width: 16, Height: 16, Pix: make([]Pixel, 256)}
+	for i := range ansiImage.Pix {
+		ansiImage.Pix[i] = Pixel{C: byte(i), ForegroundColor: byte(rnd.Intn(16)), BackgroundColor: byte(rnd.Intn(16))}
+	}
+	want := Render(ansiImage, nil).(*image.Paletted)
+	got := Render(ansiImage, &RenderOptions{Face: FontVGA16.Face(nil)}).(*image.Paletted)
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("Expected bounds %v, got %v", want.Bounds(), got.Bounds())
+	}
+	for i := range want.Pix {
+		if got.Pix[i] != want.Pix[i] {
+			t.Fatalf("Pixel %d,%d differs from the bitmap font", i%want.Stride, i/want.Stride)
+		}
+	}
+}
+
+func TestFontFaceControlGlyphs(t *testing.T) {
+	face := FontVGA16.Face(nil)
+	for _, r := range []rune{'☺', '♪', '▼', '⌂'} {
+		if _, ok := face.GlyphAdvance(r); !ok {
+			t.Errorf("Expected a glyph for %q", r)
+		}
+	}
+	if _, ok := face.GlyphAdvance(1); ok {
+		t.Error("Expected no glyph for the control code 1")
+	}
+
+	// A charset that maps a control character keeps its rune
+	cs := *CharsetCP437
+	cs[1] = 'A'
+	ansiImage := &Image{Width: 2, Height: 1, Pix: []Pixel{{C: 1, ForegroundColor: 7}, {C: 'A', ForegroundColor: 7}}}
+	img := Render(ansiImage, &RenderOptions{Face: face, Charset: &cs}).(*image.Paletted)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 8; x++ {
+			if img.ColorIndexAt(x, y) != img.ColorIndexAt(x+8, y) {
+				t.Fatalf("Expected character 1 to be drawn as 'A' at %d,%d", x, y)
+			}
+		}
+	}
+}
+
+func TestVectorFace(t *testing.T) {
+	f, err := opentype.Parse(gomono.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: 16, DPI: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer face.Close()
+
+	ansiImage := &Image{Width: 2, Height: 1, Pix: []Pixel{
+		{C: 'A', ForegroundColor: 15},
+		{C: 0x82, ForegroundColor: 15}, // é
+	}}
+	opts := &RenderOptions{Face: face, ColorModel: ColorModelRGBA}
+	img := Render(ansiImage, opts).(*image.RGBA)
+	o := opts.withDefaults()
+	size := o.cellSize()
+	if img.Bounds().Dx() != 2*size.X || img.Bounds().Dy() != size.Y {
+		t.Fatalf("Expected size %dx%d, got %v", 2*size.X, size.Y, img.Bounds())
+	}
+	var partial bool
+	for x := size.X; x < 2*size.X; x++ {
+		for y := 0; y < size.Y; y++ {
+			if c := img.RGBAAt(x, y); c.R != 0 && c.R != 255 {
+				partial = true
+			}
+		}
+	}
+	if !partial {
+		t.Error("Expected anti-aliased pixels for é")
+	}
+}
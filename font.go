@@ -0,0 +1,46 @@
+package ansi
+
+// Font is a bitmap font with a glyph for each character.
+type Font struct {
+	Width  int
+	Height int
+	// Bitmap holds the glyphs in order. Each glyph is Height rows of
+	// (Width+7)/8 bytes with the most significant bit as the leftmost pixel.
+	Bitmap []byte
+}
+
+// Built-in fonts using the tables in fonts.go
+var (
+	FontVGA8  = &Font{Width: 8, Height: 8, Bitmap: VGAFont8[:]}
+	FontVGA14 = &Font{Width: 8, Height: 14, Bitmap: VGAFont14[:]}
+	FontVGA16 = &Font{Width: 8, Height: 16, Bitmap: VGAFont16[:]}
+	// FontVGA19 is the 8x19 font of the 640x480 graphics mode derived from FontVGA16.
+	FontVGA19 = vga19()
+)
+
+// stride returns the number of bytes per row of a glyph.
+func (f *Font) stride() int {
+	return (f.Width + 7) / 8
+}
+
+// NumGlyphs returns the number of glyphs in the font.
+func (f *Font) NumGlyphs() int {
+	return len(f.Bitmap) / (f.stride() * f.Height)
+}
+
+// glyph returns the bitmap for character c or nil if the font has no glyph for it.
+func (f *Font) glyph(c int) []byte {
+	n := f.stride() * f.Height
+	if (c+1)*n > len(f.Bitmap) {
+		return nil
+	}
+	return f.Bitmap[c*n : (c+1)*n]
+}
+
+// set returns true if the pixel at x, y of a glyph returned by glyph is set.
+func (f *Font) set(glyph []byte, x, y int) bool {
+	if glyph == nil {
+		return false
+	}
+	return glyph[y*f.stride()+x/8]&(0x80>>uint(x%8)) != 0
+}
@@ -0,0 +1,196 @@
+package ansi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+var (
+	psf1Magic = []byte{0x36, 0x04}
+	psf2Magic = []byte{0x72, 0xb5, 0x4a, 0x86}
+)
+
+const psf1Mode512 = 0x01
+
+// ReadPSF reads a PC Screen Font (version 1 or 2) as used by the Linux console.
+// The Unicode table, if any, is ignored and glyphs are indexed by their position.
+func ReadPSF(r io.Reader) (*Font, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(b, psf1Magic):
+		if len(b) < 4 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		mode := b[2]
+		height := int(b[3])
+		n := 256
+		if mode&psf1Mode512 != 0 {
+			n = 512
+		}
+		if height == 0 {
+			return nil, errors.New("invalid PSF1 glyph height 0")
+		}
+		if len(b) < 4+n*height {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return &Font{Width: 8, Height: height, Bitmap: b[4 : 4+n*height]}, nil
+	case bytes.HasPrefix(b, psf2Magic):
+		if len(b) < 32 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		headerSize := int(binary.LittleEndian.Uint32(b[8:12]))
+		n := int(binary.LittleEndian.Uint32(b[16:20]))
+		charSize := int(binary.LittleEndian.Uint32(b[20:24]))
+		height := int(binary.LittleEndian.Uint32(b[24:28]))
+		width := int(binary.LittleEndian.Uint32(b[28:32]))
+		if width <= 0 || height <= 0 || charSize <= 0 || charSize != (width+7)/8*height {
+			return nil, fmt.Errorf("invalid PSF2 glyph size %dx%d (%d bytes)", width, height, charSize)
+		}
+		// Checked without multiplying so that a huge count can't overflow
+		if headerSize < 32 || headerSize > len(b) || n <= 0 || n > (len(b)-headerSize)/charSize {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return &Font{Width: width, Height: height, Bitmap: b[headerSize : headerSize+n*charSize]}, nil
+	}
+	return nil, errors.New("not a PSF font")
+}
+
+// ReadRawFont reads a raw DOS font dump (e.g. .F08, .F14, .F16, .FNT) of 256
+// glyphs that are 8 pixels wide. The height is determined from the size.
+func ReadRawFont(r io.Reader) (*Font, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 || len(b)%256 != 0 || len(b)/256 > 32 {
+		return nil, fmt.Errorf("invalid raw font size %d", len(b))
+	}
+	return &Font{Width: 8, Height: len(b) / 256, Bitmap: b}, nil
+}
+
+// ReadBDF reads a Glyph Bitmap Distribution Format font. Glyphs are placed
+// in cells the size of the font bounding box. Fonts with an ISO10646
+// charset registry are mapped to PC ASCII, otherwise the encoding is used
+// as the character directly. Glyphs for characters above 255 are ignored.
+func ReadBDF(r io.Reader) (*Font, error) {
+	var (
+		font                   *Font
+		ascent, xOffset        int
+		unicode                bool
+		encoding               = -1
+		bbxW, bbxH, bbxX, bbxY int
+		bitmapRow              = -1
+		glyph                  []byte
+	)
+	fromUnicode := make(map[rune]int, 256)
+	for i := 1; i < 256; i++ {
+		if r := pcASCIIRune(byte(i)); fromUnicode[r] == 0 {
+			fromUnicode[r] = i
+		}
+	}
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		ints := func(n int) ([]int, error) {
+			if len(fields) < n+1 {
+				return nil, fmt.Errorf("line %d: expected %d values for %s", line, n, fields[0])
+			}
+			v := make([]int, n)
+			for i := range v {
+				var err error
+				if v[i], err = strconv.Atoi(fields[i+1]); err != nil {
+					return nil, fmt.Errorf("line %d: %s", line, err)
+				}
+			}
+			return v, nil
+		}
+
+		if bitmapRow >= 0 && fields[0] != "ENDCHAR" {
+			row, err := hex.DecodeString(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid bitmap: %s", line, err)
+			}
+			if glyph != nil {
+				y := ascent - (bbxH + bbxY) + bitmapRow
+				for x := 0; x < bbxW && x < len(row)*8; x++ {
+					fx := bbxX - xOffset + x
+					if row[x/8]&(0x80>>uint(x%8)) != 0 && y >= 0 && y < font.Height && fx >= 0 && fx < font.Width {
+						glyph[y*font.stride()+fx/8] |= 0x80 >> uint(fx%8)
+					}
+				}
+			}
+			bitmapRow++
+			continue
+		}
+
+		switch fields[0] {
+		case "FONTBOUNDINGBOX":
+			v, err := ints(4)
+			if err != nil {
+				return nil, err
+			}
+			if v[0] <= 0 || v[1] <= 0 {
+				return nil, fmt.Errorf("line %d: invalid bounding box %dx%d", line, v[0], v[1])
+			}
+			font = &Font{Width: v[0], Height: v[1]}
+			font.Bitmap = make([]byte, 256*font.stride()*font.Height)
+			ascent = v[1] + v[3]
+			xOffset = v[2]
+		case "CHARSET_REGISTRY":
+			unicode = len(fields) > 1 && strings.EqualFold(strings.Trim(fields[1], `"`), "ISO10646")
+		case "ENCODING":
+			v, err := ints(1)
+			if err != nil {
+				return nil, err
+			}
+			encoding = v[0]
+		case "BBX":
+			v, err := ints(4)
+			if err != nil {
+				return nil, err
+			}
+			bbxW, bbxH, bbxX, bbxY = v[0], v[1], v[2], v[3]
+		case "BITMAP":
+			if font == nil {
+				return nil, fmt.Errorf("line %d: BITMAP before FONTBOUNDINGBOX", line)
+			}
+			c := encoding
+			if unicode {
+				var ok bool
+				if c, ok = fromUnicode[rune(encoding)]; !ok {
+					c = -1
+				}
+			}
+			glyph = nil
+			if c >= 0 && c < 256 {
+				glyph = font.glyph(c)
+			}
+			bitmapRow = 0
+		case "ENDCHAR":
+			bitmapRow = -1
+			encoding = -1
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if font == nil {
+		return nil, errors.New("not a BDF font")
+	}
+	return font, nil
+}
@@ -0,0 +1,96 @@
+package ansi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestReadPSF(t *testing.T) {
+	psf1 := append([]byte{0x36, 0x04, 0, 16}, VGAFont16[:]...)
+	f, err := ReadPSF(bytes.NewReader(psf1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Width != 8 || f.Height != 16 || f.NumGlyphs() != 256 {
+		t.Errorf("PSF1: expected 8x16 with 256 glyphs, got %dx%d with %d", f.Width, f.Height, f.NumGlyphs())
+	}
+
+	psf2 := make([]byte, 32)
+	copy(psf2, psf2Magic)
+	for i, v := range []uint32{0, 32, 0, 2, 2 * 10, 10, 12} {
+		binary.LittleEndian.PutUint32(psf2[4+i*4:], v)
+	}
+	psf2 = append(psf2, make([]byte, 2*2*10)...)
+	psf2[32+20] = 0xff // first row of the second glyph
+	f, err = ReadPSF(bytes.NewReader(psf2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Width != 12 || f.Height != 10 || f.NumGlyphs() != 2 {
+		t.Errorf("PSF2: expected 12x10 with 2 glyphs, got %dx%d with %d", f.Width, f.Height, f.NumGlyphs())
+	}
+	if !f.set(f.glyph(1), 0, 0) || f.set(f.glyph(0), 0, 0) {
+		t.Error("PSF2: glyph bitmap mismatch")
+	}
+
+	// A glyph count and size whose product overflows
+	huge := make([]byte, 64)
+	copy(huge, psf2Magic)
+	for i, v := range []uint32{0, 32, 0, 0xffffffff, 0xffffffff, 0xffffffff, 8} {
+		binary.LittleEndian.PutUint32(huge[4+i*4:], v)
+	}
+	if _, err := ReadPSF(bytes.NewReader(huge)); err == nil {
+		t.Error("PSF2: expected error for huge glyph count")
+	}
+}
+
+func TestReadRawFont(t *testing.T) {
+	f, err := ReadRawFont(bytes.NewReader(VGAFont14[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Width != 8 || f.Height != 14 {
+		t.Errorf("Expected 8x14, got %dx%d", f.Width, f.Height)
+	}
+	if _, err := ReadRawFont(bytes.NewReader(make([]byte, 100))); err == nil {
+		t.Error("Expected error for invalid size")
+	}
+}
+
+func TestReadBDF(t *testing.T) {
+	const bdf = `STARTFONT 2.1
+FONT test
+SIZE 8 75 75
+FONTBOUNDINGBOX 8 4 0 -1
+STARTPROPERTIES 1
+CHARSET_REGISTRY "ISO10646"
+ENDPROPERTIES
+CHARS 1
+STARTCHAR fullblock
+ENCODING 9608
+SWIDTH 500 0
+DWIDTH 8 0
+BBX 4 2 2 0
+BITMAP
+F0
+90
+ENDCHAR
+ENDFONT
+`
+	f, err := ReadBDF(strings.NewReader(bdf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Width != 8 || f.Height != 4 {
+		t.Fatalf("Expected 8x4, got %dx%d", f.Width, f.Height)
+	}
+	// U+2588 full block is 219 in PC ASCII. The baseline is at row 3 so
+	// the 2 row bitmap covers rows 1 and 2 starting at column 2.
+	g := f.glyph(219)
+	want := []byte{0x00, 0x3c, 0x24, 0x00}
+	if !bytes.Equal(g, want) {
+		t.Errorf("Expected glyph %x, got %x", want, g)
+	}
+}
@@ -0,0 +1,84 @@
+package ansi
+
+import (
+	"image"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// RenderAnimatedGIF writes img as a looping GIF that shows blinking text at
+// the VGA blink rate. Slow and fast blink each get their own on and off
+// frames. An image without blinking text is written as a single frame.
+// FilterLinear is ignored and 24-bit colors are replaced by the closest
+// palette color since GIF frames must be paletted.
+func RenderAnimatedGIF(w io.Writer, img *Image, opts *RenderOptions) error {
+	o := opts.withDefaults()
+	o.Filter = FilterNearest
+	o.ColorModel = ColorModelPaletted
+
+	var hasSlow, hasFast bool
+	// The cursor blinks at the fast rate.
+	cursorBlink := o.CursorBlink && o.Cursor != CursorNone && img.cursorVisible()
+	if cursorBlink {
+		hasFast = true
+	}
+	for _, p := range img.Pix {
+		switch p.Blink {
+		case BlinkSlow:
+			hasSlow = true
+		case BlinkFast:
+			hasFast = true
+		}
+	}
+
+	// Frames are in steps of half the fast blink period. Slow blink
+	// toggles every 2 steps and fast blink every step.
+	step := BlinkFast.period() / 2
+	steps := 1
+	stride := 1
+	switch {
+	case hasFast && hasSlow:
+		steps = 4
+	case hasFast:
+		steps = 2
+	case hasSlow:
+		steps = 4
+		stride = 2
+	}
+
+	anim := &gif.GIF{}
+	for i := 0; i < steps; i += stride {
+		frame := blinkFrame(img, i%2 == 0, i < 2)
+		fo := o
+		if cursorBlink && i%2 != 0 {
+			fo.cursorOff = true
+		}
+		anim.Image = append(anim.Image, Render(frame, &fo).(*image.Paletted))
+		anim.Delay = append(anim.Delay, int(time.Duration(stride)*step/(10*time.Millisecond)))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// blinkFrame returns img with the blinking characters hidden unless their
+// blink kind is visible. The cells keep their background.
+func blinkFrame(img *Image, fastVisible, slowVisible bool) *Image {
+	if fastVisible && slowVisible {
+		return img
+	}
+	frame := &Image{
+		Pix:          append([]Pixel(nil), img.Pix...),
+		Width:        img.Width,
+		Height:       img.Height,
+		Cursor:       img.Cursor,
+		CursorHidden: img.CursorHidden,
+		HasCursor:    img.HasCursor,
+	}
+	for i, p := range frame.Pix {
+		if (p.Blink == BlinkFast && !fastVisible) || (p.Blink == BlinkSlow && !slowVisible) {
+			frame.Pix[i].ForegroundColor = p.BackgroundColor
+			frame.Pix[i].ForegroundRGB = p.BackgroundRGB
+		}
+	}
+	return frame
+}
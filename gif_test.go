@@ -0,0 +1,92 @@
+package ansi
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"strings"
+	"testing"
+)
+
+func TestRenderAnimatedGIF(t *testing.T) {
+	tests := []struct {
+		pix    []Pixel
+		frames int
+		delay  int
+	}{
+		{[]Pixel{{C: 'A', ForegroundColor: 7}}, 1, 11},
+		{[]Pixel{{C: 'A', ForegroundColor: 7, Blink: BlinkSlow}}, 2, 22},
+		{[]Pixel{{C: 'A', ForegroundColor: 7, Blink: BlinkFast}}, 2, 11},
+		{[]Pixel{{C: 'A', ForegroundColor: 7, Blink: BlinkSlow}, {C: 'B', ForegroundColor: 7, Blink: BlinkFast}}, 4, 11},
+	}
+	for i, test := range tests {
+		img := &Image{Width: len(test.pix), Height: 1, Pix: test.pix}
+		var buf bytes.Buffer
+		if err := RenderAnimatedGIF(&buf, img, nil); err != nil {
+			t.Fatal(err)
+		}
+		anim, err := gif.DecodeAll(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(anim.Image) != test.frames {
+			t.Errorf("%d: expected %d frames, got %d", i, test.frames, len(anim.Image))
+		}
+		if anim.Delay[0] != test.delay {
+			t.Errorf("%d: expected delay %d, got %d", i, test.delay, anim.Delay[0])
+		}
+	}
+}
+
+func TestRenderAnimatedGIFTrueColorBlink(t *testing.T) {
+	for _, p := range []Pixel{
+		{C: 0xdb, ForegroundRGB: color.RGBA{255, 128, 0, 255}, ForegroundColor: 6, Blink: BlinkSlow},
+		{C: 0xdb, ForegroundRGB: color.RGBA{255, 128, 0, 255}, ForegroundColor: 6, BackgroundRGB: color.RGBA{0, 0, 64, 255}, Blink: BlinkSlow},
+	} {
+		img := &Image{Width: 1, Height: 1, Pix: []Pixel{p}}
+		var buf bytes.Buffer
+		if err := RenderAnimatedGIF(&buf, img, nil); err != nil {
+			t.Fatal(err)
+		}
+		anim, err := gif.DecodeAll(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(anim.Image) != 2 {
+			t.Fatalf("Expected 2 frames, got %d", len(anim.Image))
+		}
+		on, off := anim.Image[0], anim.Image[1]
+		if on.At(4, 8) == off.At(4, 8) {
+			t.Errorf("%+v: expected the glyph to be hidden in the second frame", p)
+		}
+		bg := Render(&Image{Width: 1, Height: 1, Pix: []Pixel{{C: ' ', BackgroundColor: p.BackgroundColor, BackgroundRGB: p.BackgroundRGB}}}, &RenderOptions{ColorModel: ColorModelPaletted})
+		if off.At(4, 8) != bg.At(4, 8) {
+			t.Errorf("%+v: expected the hidden glyph to be the background color", p)
+		}
+	}
+}
+
+func TestRenderAnimatedGIFCursorBlink(t *testing.T) {
+	img, err := Parse(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := RenderAnimatedGIF(&buf, img, &RenderOptions{Cursor: CursorBlock, CursorBlink: true}); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 2 {
+		t.Fatalf("Expected 2 frames, got %d", len(anim.Image))
+	}
+	on, off := anim.Image[0], anim.Image[1]
+	if on.Bounds() != off.Bounds() || on.Bounds().Dx() != 6*8 {
+		t.Fatalf("Expected both frames to be 48 pixels wide, got %v and %v", on.Bounds(), off.Bounds())
+	}
+	if on.ColorIndexAt(44, 8) != 7 || off.ColorIndexAt(44, 8) != 0 {
+		t.Errorf("Expected the cursor only in the first frame, got %d and %d", on.ColorIndexAt(44, 8), off.ColorIndexAt(44, 8))
+	}
+}
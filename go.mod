@@ -1,3 +1,7 @@
 module github.com/samuel/ansi
 
-go 1.16
+go 1.18
+
+require golang.org/x/image v0.18.0
+
+require golang.org/x/text v0.16.0 // indirect
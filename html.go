@@ -0,0 +1,235 @@
+package ansi
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// HTMLOptions are the options for WriteHTML.
+type HTMLOptions struct {
+	// InlineStyles sets colors using style attributes rather than classes.
+	InlineStyles bool
+	// MergeRuns wraps runs of cells with the same attributes in a single
+	// span rather than one span per cell.
+	MergeRuns bool
+	// ClassPrefix is prepended to all class and animation names. It may only
+	// contain ASCII letters, digits, '-', and '_' and can't start with a
+	// digit. The default is "ansi-".
+	ClassPrefix string
+	// NoStylesheet omits the <style> element when using classes so that
+	// the page can provide its own.
+	NoStylesheet bool
+	// FontURL, if set, is referenced by an @font-face rule and used for the text.
+	FontURL string
+	// FontFamily is the name given to the font at FontURL. The default is ClassPrefix + "font".
+	FontFamily string
+	// Palette is the 16 colors used for the output. The default is VGAPalette.
+	Palette Palette
+}
+
+// WriteHTML writes img as a <pre> element preceded by a <style> element.
+// Characters are converted to Unicode so the text remains selectable and
+// searchable, and blinking is implemented with a CSS animation.
+func WriteHTML(w io.Writer, img *Image, opts *HTMLOptions) error {
+	o := HTMLOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.ClassPrefix == "" {
+		o.ClassPrefix = "ansi-"
+	}
+	if !validClassPrefix(o.ClassPrefix) {
+		return fmt.Errorf("invalid class prefix %q", o.ClassPrefix)
+	}
+	if o.FontFamily == "" {
+		o.FontFamily = o.ClassPrefix + "font"
+	}
+	o.Palette = o.Palette.complete()
+
+	var hasBlink bool
+	for _, p := range img.Pix {
+		if p.Blink != BlinkNone {
+			hasBlink = true
+			break
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if !o.InlineStyles && !o.NoStylesheet || o.InlineStyles && (hasBlink || o.FontURL != "") {
+		o.writeStylesheet(bw)
+	}
+
+	fmt.Fprintf(bw, `<pre class="%s"`, o.ClassPrefix+"image")
+	if o.InlineStyles {
+		fmt.Fprintf(bw, ` style="%s"`, html.EscapeString(o.preStyle()))
+	}
+	bw.WriteByte('>')
+	for y := 0; y < img.Height; y++ {
+		if y != 0 {
+			bw.WriteByte('\n')
+		}
+		row := img.Pix[y*img.Width : (y+1)*img.Width]
+		end := len(row)
+		for end > 0 && row[end-1] == (Pixel{}) {
+			end--
+		}
+		open := false
+		var last Pixel
+		for x, p := range row[:end] {
+			if p == (Pixel{}) {
+				p = Pixel{C: ' ', ForegroundColor: 7}
+			}
+			if x == 0 || !o.MergeRuns || p.ForegroundColor != last.ForegroundColor || p.BackgroundColor != last.BackgroundColor || p.Blink != last.Blink ||
+				p.ForegroundRGB != last.ForegroundRGB || p.BackgroundRGB != last.BackgroundRGB {
+				if open {
+					bw.WriteString("</span>")
+					open = false
+				}
+				if p.ForegroundColor != 7 || p.BackgroundColor != 0 || p.Blink != BlinkNone || p.ForegroundRGB.A != 0 || p.BackgroundRGB.A != 0 {
+					o.writeSpan(bw, p)
+					open = true
+				}
+				last = p
+			}
+			switch r := p.text(); r {
+			case '&':
+				bw.WriteString("&amp;")
+			case '<':
+				bw.WriteString("&lt;")
+			case '>':
+				bw.WriteString("&gt;")
+			default:
+				bw.WriteRune(r)
+			}
+		}
+		if open {
+			bw.WriteString("</span>")
+		}
+	}
+	bw.WriteString("</pre>\n")
+	return bw.Flush()
+}
+
+func (o *HTMLOptions) writeStylesheet(w *bufio.Writer) {
+	w.WriteString("<style>\n")
+	if o.FontURL != "" {
+		fmt.Fprintf(w, "@font-face { font-family: %s; src: url(%s); }\n", cssString(o.FontFamily), cssString(o.FontURL))
+	}
+	fmt.Fprintf(w, "@keyframes %sblink { 50%% { color: transparent; } }\n", o.ClassPrefix)
+	if !o.InlineStyles {
+		fmt.Fprintf(w, ".%simage { %s }\n", o.ClassPrefix, o.preStyle())
+		for i, c := range o.Palette {
+			fmt.Fprintf(w, ".%sf%d { color: %s; }\n", o.ClassPrefix, i, cssColor(c))
+		}
+		for i, c := range o.Palette {
+			fmt.Fprintf(w, ".%sb%d { background-color: %s; }\n", o.ClassPrefix, i, cssColor(c))
+		}
+		for _, b := range []Blink{BlinkSlow, BlinkFast} {
+			fmt.Fprintf(w, ".%s { %s }\n", o.blinkClass(b), o.blinkStyle(b))
+		}
+	}
+	w.WriteString("</style>\n")
+}
+
+func (o *HTMLOptions) preStyle() string {
+	family := "monospace"
+	if o.FontURL != "" {
+		family = cssString(o.FontFamily) + ", monospace"
+	}
+	return fmt.Sprintf("color: %s; background-color: %s; font-family: %s; line-height: 1;",
+		cssColor(o.Palette[7]), cssColor(o.Palette[0]), family)
+}
+
+// writeSpan opens a span with the colors of p. 24-bit colors always use a
+// style attribute since there are no classes for them.
+func (o *HTMLOptions) writeSpan(w *bufio.Writer, p Pixel) {
+	if o.InlineStyles {
+		fmt.Fprintf(w, `<span style="color: %s; background-color: %s;`,
+			cssColor(p.foreground(o.Palette)), cssColor(p.background(o.Palette)))
+		if p.Blink != BlinkNone {
+			w.WriteByte(' ')
+			w.WriteString(o.blinkStyle(p.Blink))
+		}
+		w.WriteString(`">`)
+		return
+	}
+	var classes []string
+	if p.ForegroundRGB.A == 0 {
+		classes = append(classes, fmt.Sprintf("%sf%d", o.ClassPrefix, p.ForegroundColor&15))
+	}
+	if p.BackgroundRGB.A == 0 {
+		classes = append(classes, fmt.Sprintf("%sb%d", o.ClassPrefix, p.BackgroundColor&15))
+	}
+	if p.Blink != BlinkNone {
+		classes = append(classes, o.blinkClass(p.Blink))
+	}
+	w.WriteString("<span")
+	if len(classes) != 0 {
+		fmt.Fprintf(w, ` class="%s"`, strings.Join(classes, " "))
+	}
+	if p.ForegroundRGB.A != 0 || p.BackgroundRGB.A != 0 {
+		w.WriteString(` style="`)
+		if p.ForegroundRGB.A != 0 {
+			fmt.Fprintf(w, "color: %s;", cssColor(p.ForegroundRGB))
+		}
+		if p.BackgroundRGB.A != 0 {
+			if p.ForegroundRGB.A != 0 {
+				w.WriteByte(' ')
+			}
+			fmt.Fprintf(w, "background-color: %s;", cssColor(p.BackgroundRGB))
+		}
+		w.WriteByte('"')
+	}
+	w.WriteByte('>')
+}
+
+func (o *HTMLOptions) blinkClass(b Blink) string {
+	if b == BlinkFast {
+		return o.ClassPrefix + "blink-fast"
+	}
+	return o.ClassPrefix + "blink-slow"
+}
+
+func (o *HTMLOptions) blinkStyle(b Blink) string {
+	return fmt.Sprintf("animation: %sblink %dms step-end infinite;", o.ClassPrefix, b.period().Milliseconds())
+}
+
+// validClassPrefix returns true if prefix can start a CSS class name
+// without escaping.
+func validClassPrefix(prefix string) bool {
+	for i := 0; i < len(prefix); i++ {
+		switch c := prefix[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-', c == '_':
+		case c >= '0' && c <= '9' && i != 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// cssString returns s as a quoted CSS string. Quotes, backslashes, control
+// characters, and characters special to HTML are escaped so the result is
+// safe in a <style> element or, after HTML escaping, a style attribute.
+func cssString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r < 0x20 || r == 0x7f || strings.ContainsRune(`"'\<>&`, r):
+			fmt.Fprintf(&b, "\\%x ", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func cssColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
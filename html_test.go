@@ -0,0 +1,143 @@
+package ansi
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTML(t *testing.T) {
+	ab := []Pixel{
+		{C: 'A', ForegroundColor: 12, BackgroundColor: 1},
+		{C: 'B', ForegroundColor: 12, BackgroundColor: 1},
+		{C: '<', ForegroundColor: 7},
+	}
+	tests := []struct {
+		name string
+		pix  []Pixel
+		opts HTMLOptions
+		want []string
+		not  []string
+	}{
+		{
+			name: "classes",
+			pix:  ab,
+			opts: HTMLOptions{NoStylesheet: true},
+			want: []string{`<pre class="ansi-image"><span class="ansi-f12 ansi-b1">A</span><span class="ansi-f12 ansi-b1">B</span>&lt;</pre>` + "\n"},
+			not:  []string{"<style>"},
+		},
+		{
+			name: "merged classes",
+			pix:  ab,
+			opts: HTMLOptions{MergeRuns: true, ClassPrefix: "x-"},
+			want: []string{
+				".x-f12 { color: #5555ff; }",
+				".x-b1 { background-color: #aa0000; }",
+				`<pre class="x-image"><span class="x-f12 x-b1">AB</span>&lt;</pre>`,
+			},
+		},
+		{
+			name: "merged inline",
+			pix:  ab,
+			opts: HTMLOptions{MergeRuns: true, InlineStyles: true},
+			want: []string{`<pre class="ansi-image" style="color: #aaaaaa; background-color: #000000; font-family: monospace; line-height: 1;">` +
+				`<span style="color: #5555ff; background-color: #aa0000;">AB</span>&lt;</pre>`},
+			not: []string{"<style>", "class=\"ansi-f"},
+		},
+		{
+			name: "blink classes",
+			pix:  []Pixel{{C: 'A', ForegroundColor: 7, Blink: BlinkSlow}, {C: 'B', ForegroundColor: 7, Blink: BlinkFast}},
+			want: []string{
+				"@keyframes ansi-blink { 50% { color: transparent; } }",
+				".ansi-blink-slow { animation: ansi-blink 457ms step-end infinite; }",
+				".ansi-blink-fast { animation: ansi-blink 228ms step-end infinite; }",
+				`<span class="ansi-f7 ansi-b0 ansi-blink-slow">A</span><span class="ansi-f7 ansi-b0 ansi-blink-fast">B</span>`,
+			},
+		},
+		{
+			name: "blink inline",
+			pix:  []Pixel{{C: 'A', ForegroundColor: 7, Blink: BlinkSlow}},
+			opts: HTMLOptions{InlineStyles: true},
+			want: []string{
+				"<style>\n@keyframes ansi-blink { 50% { color: transparent; } }\n</style>\n",
+				`<span style="color: #aaaaaa; background-color: #000000; animation: ansi-blink 457ms step-end infinite;">A</span>`,
+			},
+		},
+		{
+			name: "font",
+			pix:  ab[2:],
+			opts: HTMLOptions{FontURL: "fonts/vga.woff"},
+			want: []string{
+				`@font-face { font-family: "ansi-font"; src: url("fonts/vga.woff"); }`,
+				`.ansi-image { color: #aaaaaa; background-color: #000000; font-family: "ansi-font", monospace; line-height: 1; }`,
+			},
+		},
+		{
+			name: "font inline",
+			pix:  ab[2:],
+			opts: HTMLOptions{FontURL: "vga.woff", FontFamily: "VGA", InlineStyles: true},
+			want: []string{
+				`@font-face { font-family: "VGA"; src: url("vga.woff"); }`,
+				`style="color: #aaaaaa; background-color: #000000; font-family: &#34;VGA&#34;, monospace; line-height: 1;"`,
+			},
+		},
+		{
+			name: "24-bit",
+			pix:  []Pixel{{C: 'A', ForegroundColor: 6, ForegroundRGB: color.RGBA{200, 100, 0, 255}, BackgroundColor: 1}},
+			opts: HTMLOptions{NoStylesheet: true},
+			want: []string{`<span class="ansi-b1" style="color: #c86400;">A</span>`},
+		},
+	}
+	for _, test := range tests {
+		img := &Image{Width: len(test.pix), Height: 1, Pix: test.pix}
+		var buf bytes.Buffer
+		if err := WriteHTML(&buf, img, &test.opts); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		out := buf.String()
+		for _, s := range test.want {
+			if !strings.Contains(out, s) {
+				t.Errorf("%s: expected %q in\n%s", test.name, s, out)
+			}
+		}
+		for _, s := range test.not {
+			if strings.Contains(out, s) {
+				t.Errorf("%s: unexpected %q in\n%s", test.name, s, out)
+			}
+		}
+	}
+}
+
+func TestWriteHTMLEscaping(t *testing.T) {
+	img := &Image{Width: 1, Height: 1, Pix: []Pixel{{C: 'A', ForegroundColor: 7}}}
+	for _, inline := range []bool{false, true} {
+		var buf bytes.Buffer
+		err := WriteHTML(&buf, img, &HTMLOptions{
+			InlineStyles: inline,
+			FontURL:      `x");}</style><script>alert(1)</script>`,
+			FontFamily:   `a"b'c`,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, bad := range []string{"<script>", `a"b`, "a'b"} {
+			if strings.Contains(out, bad) {
+				t.Errorf("inline=%t: output contains %q:\n%s", inline, bad, out)
+			}
+		}
+		if strings.Count(out, "</style>") > 1 {
+			t.Errorf("inline=%t: style element closed early:\n%s", inline, out)
+		}
+		if !strings.Contains(out, `\3c /style\3e `) {
+			t.Errorf("inline=%t: expected escaped font URL:\n%s", inline, out)
+		}
+	}
+
+	for _, prefix := range []string{`x"><script>`, "a b", "1a"} {
+		if err := WriteHTML(&bytes.Buffer{}, img, &HTMLOptions{ClassPrefix: prefix}); err == nil {
+			t.Errorf("Expected an error for class prefix %q", prefix)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package ansi
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	idfHeaderSize = 12
+	idfFontSize   = 256 * 16
+)
+
+// ReadIDF reads an iCE Draw IDF file. The character and attribute pairs
+// are run length encoded, followed by an 8x16 font and a 16 color palette.
+// IDF always uses iCE colors.
+func ReadIDF(r io.Reader) (*Document, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b, _ = splitSauce(b)
+	if len(b) < idfHeaderSize+idfFontSize+48 {
+		return nil, errors.New("IDF file too short")
+	}
+	if b[0] != 0x04 || string(b[1:4]) != "1.4" && string(b[1:4]) != "1.3" {
+		return nil, errors.New("not an IDF file")
+	}
+	x1 := int(binary.LittleEndian.Uint16(b[4:6]))
+	x2 := int(binary.LittleEndian.Uint16(b[8:10]))
+	width := x2 - x1 + 1
+	if width <= 0 {
+		return nil, errors.New("invalid IDF width")
+	}
+
+	palette, err := ParseXBINPalette(b[len(b)-48:])
+	if err != nil {
+		return nil, err
+	}
+	font := b[len(b)-48-idfFontSize : len(b)-48]
+	data := b[idfHeaderSize : len(b)-48-idfFontSize]
+
+	// A run is the 16-bit value 1 followed by a 16-bit count and the
+	// character and attribute to repeat.
+	var pairs []byte
+	for i := 0; i+1 < len(data); {
+		if data[i] == 1 && data[i+1] == 0 && i+6 <= len(data) {
+			n := int(binary.LittleEndian.Uint16(data[i+2 : i+4]))
+			for j := 0; j < n; j++ {
+				pairs = append(pairs, data[i+4], data[i+5])
+			}
+			i += 6
+			continue
+		}
+		pairs = append(pairs, data[i], data[i+1])
+		i += 2
+	}
+	return &Document{
+		Image:     readAttrPairs(pairs, width, true),
+		Font:      &Font{Width: 8, Height: 16, Bitmap: append([]byte(nil), font...)},
+		Palette:   palette,
+		ICEColors: true,
+	}, nil
+}
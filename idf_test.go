@@ -0,0 +1,36 @@
+package ansi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestReadIDF(t *testing.T) {
+	b := []byte("\x041.4\x00\x00\x00\x00\x03\x00\x01\x00")
+	b = append(b, 'A', 0x1f)
+	b = append(b, 1, 0, 5, 0, 0xdb, 0xc4) // run of 5
+	b = append(b, VGAFont16[:]...)
+	pal := make([]byte, 48)
+	for i := range pal {
+		pal[i] = 63
+	}
+	b = append(b, pal...)
+
+	doc, err := ReadIDF(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := doc.Image
+	if img.Width != 4 || img.Height != 2 {
+		t.Fatalf("Expected 4x2, got %dx%d", img.Width, img.Height)
+	}
+	run := Pixel{C: 0xdb, ForegroundColor: 1, BackgroundColor: 9}
+	want := []Pixel{{C: 'A', ForegroundColor: 15, BackgroundColor: 4}, run, run, run, run, run, {}, {}}
+	if !reflect.DeepEqual(img.Pix, want) {
+		t.Errorf("Expected %+v, got %+v", want, img.Pix)
+	}
+	if len(doc.Palette) != 16 || doc.Font.Height != 16 || !doc.ICEColors {
+		t.Errorf("Expected a palette, 8x16 font, and iCE colors")
+	}
+}
@@ -0,0 +1,234 @@
+package ansi
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Palette is a set of 16 colors indexed by Pixel foreground and background
+// colors. The order is the ANSI order (0 black, 1 red, 2 green, 3 yellow,
+// 4 blue, 5 magenta, 6 cyan, 7 white) followed by the high intensity colors.
+// When a palette in the options doesn't have 16 colors the missing ones are
+// the VGA colors and any extra are ignored.
+type Palette []color.RGBA
+
+// The built-in palettes are only returned as copies so that changing one
+// can't affect other users.
+var (
+	vgaPalette       = Palette(append([]color.RGBA(nil), VGAPalette...))
+	workbenchPalette = Palette{
+		{R: 170, G: 170, B: 170, A: 255},
+		{R: 0, G: 0, B: 0, A: 255},
+		{R: 255, G: 255, B: 255, A: 255},
+		{R: 102, G: 136, B: 187, A: 255},
+		{R: 0, G: 0, B: 255, A: 255},
+		{R: 255, G: 0, B: 255, A: 255},
+		{R: 0, G: 255, B: 255, A: 255},
+		{R: 255, G: 255, B: 255, A: 255},
+		{R: 170, G: 170, B: 170, A: 255},
+		{R: 0, G: 0, B: 0, A: 255},
+		{R: 255, G: 255, B: 255, A: 255},
+		{R: 102, G: 136, B: 187, A: 255},
+		{R: 0, G: 0, B: 255, A: 255},
+		{R: 255, G: 0, B: 255, A: 255},
+		{R: 0, G: 255, B: 255, A: 255},
+		{R: 255, G: 255, B: 255, A: 255},
+	}
+	solarizedPalette = Palette{
+		{R: 0x07, G: 0x36, B: 0x42, A: 255},
+		{R: 0xdc, G: 0x32, B: 0x2f, A: 255},
+		{R: 0x85, G: 0x99, B: 0x00, A: 255},
+		{R: 0xb5, G: 0x89, B: 0x00, A: 255},
+		{R: 0x26, G: 0x8b, B: 0xd2, A: 255},
+		{R: 0xd3, G: 0x36, B: 0x82, A: 255},
+		{R: 0x2a, G: 0xa1, B: 0x98, A: 255},
+		{R: 0xee, G: 0xe8, B: 0xd5, A: 255},
+		{R: 0x00, G: 0x2b, B: 0x36, A: 255},
+		{R: 0xcb, G: 0x4b, B: 0x16, A: 255},
+		{R: 0x58, G: 0x6e, B: 0x75, A: 255},
+		{R: 0x65, G: 0x7b, B: 0x83, A: 255},
+		{R: 0x83, G: 0x94, B: 0x96, A: 255},
+		{R: 0x6c, G: 0x71, B: 0xc4, A: 255},
+		{R: 0x93, G: 0xa1, B: 0xa1, A: 255},
+		{R: 0xfd, G: 0xf6, B: 0xe3, A: 255},
+	}
+)
+
+// PaletteVGA returns the default VGA colors.
+func PaletteVGA() Palette {
+	return vgaPalette.clone()
+}
+
+// PaletteCGA returns the CGA colors, which are the same as the default VGA colors.
+func PaletteCGA() Palette {
+	return vgaPalette.clone()
+}
+
+// PaletteEGA returns the default EGA colors, which are the same as the
+// default VGA colors.
+func PaletteEGA() Palette {
+	return vgaPalette.clone()
+}
+
+// PaletteWorkbench returns the Amiga Workbench 2 pens followed by the
+// extra colors used by Amiga ANSI editors.
+func PaletteWorkbench() Palette {
+	return workbenchPalette.clone()
+}
+
+// PaletteXterm returns the default xterm colors.
+func PaletteXterm() Palette {
+	return Palette(xtermSystemColors[:]).clone()
+}
+
+// PaletteSolarized returns the Solarized dark terminal colors.
+func PaletteSolarized() Palette {
+	return solarizedPalette.clone()
+}
+
+func (p Palette) clone() Palette {
+	return append(Palette(nil), p...)
+}
+
+// complete returns p if it has 16 colors and otherwise a copy with the
+// missing colors taken from the VGA palette and any extra dropped.
+func (p Palette) complete() Palette {
+	if len(p) == 16 {
+		return p
+	}
+	c := vgaPalette.clone()
+	copy(c, p)
+	return c
+}
+
+// vgaToANSI maps between the VGA attribute color order (blue is 1, red is 4)
+// and the ANSI order used by Pixel. It's its own inverse.
+var vgaToANSI = [16]byte{0, 4, 2, 6, 1, 5, 3, 7, 8, 12, 10, 14, 9, 13, 11, 15}
+
+// colors returns the palette as a color.Palette for image.Paletted.
+func (p Palette) colors() color.Palette {
+	c := make(color.Palette, len(p))
+	for i, rgb := range p {
+		c[i] = rgb
+	}
+	return c
+}
+
+// foreground returns the text color of p using pal for indexed colors.
+func (p Pixel) foreground(pal Palette) color.RGBA {
+	if p.ForegroundRGB.A != 0 {
+		return p.ForegroundRGB
+	}
+	return pal[p.ForegroundColor&15]
+}
+
+// background returns the background color of p using pal for indexed colors.
+func (p Pixel) background(pal Palette) color.RGBA {
+	if p.BackgroundRGB.A != 0 {
+		return p.BackgroundRGB
+	}
+	return pal[p.BackgroundColor&15]
+}
+
+// nearest returns the index of the closest color in the palette.
+func (p Palette) nearest(c color.RGBA) byte {
+	best := 0
+	bestDist := -1
+	for i, x := range p {
+		dr := int(x.R) - int(c.R)
+		dg := int(x.G) - int(c.G)
+		db := int(x.B) - int(c.B)
+		if d := dr*dr + dg*dg + db*db; bestDist < 0 || d < bestDist {
+			best = i
+			bestDist = d
+		}
+	}
+	return byte(best)
+}
+
+// ParseXBINPalette decodes a 48 byte XBIN (or VGA DAC) palette of 6-bit RGB
+// values in VGA attribute order.
+func ParseXBINPalette(b []byte) (Palette, error) {
+	if len(b) != 48 {
+		return nil, fmt.Errorf("XBIN palette must be 48 bytes, got %d", len(b))
+	}
+	p := make(Palette, 16)
+	for i := range p {
+		c := b[i*3 : i*3+3]
+		p[vgaToANSI[i]] = color.RGBA{R: scale6Bit(c[0]), G: scale6Bit(c[1]), B: scale6Bit(c[2]), A: 255}
+	}
+	return p, nil
+}
+
+// scale6Bit scales a 6-bit VGA DAC value to 8 bits.
+func scale6Bit(v byte) byte {
+	v &= 63
+	return v<<2 | v>>4
+}
+
+// ParseOSC4 applies the OSC 4 (set color) sequences in s to a copy of
+// base. For example "\x1b]4;1;rgb:ff/00/00\x07" sets color 1 to red.
+// Colors are given as rgb:R/G/B with 1 to 4 hex digits for each component,
+// or #RRGGBB. Sequences for colors beyond the palette are ignored.
+func ParseOSC4(s string, base Palette) (Palette, error) {
+	p := append(Palette(nil), base...)
+	for {
+		i := strings.Index(s, "\x1b]4;")
+		if i < 0 {
+			return p, nil
+		}
+		s = s[i+4:]
+		end := strings.IndexAny(s, "\x07\x1b")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated OSC 4 sequence %q", s)
+		}
+		params := strings.Split(s[:end], ";")
+		s = s[end:]
+		if len(params)%2 != 0 {
+			return nil, fmt.Errorf("invalid OSC 4 parameters %q", strings.Join(params, ";"))
+		}
+		for j := 0; j < len(params); j += 2 {
+			n, err := strconv.Atoi(params[j])
+			if err != nil {
+				return nil, fmt.Errorf("invalid OSC 4 color index %q", params[j])
+			}
+			c, err := parseXColor(params[j+1])
+			if err != nil {
+				return nil, err
+			}
+			if n >= 0 && n < len(p) {
+				p[n] = c
+			}
+		}
+	}
+}
+
+// parseXColor parses an X11 color specification in rgb:R/G/B or #RRGGBB form.
+func parseXColor(spec string) (color.RGBA, error) {
+	var parts []string
+	switch {
+	case strings.HasPrefix(spec, "rgb:"):
+		parts = strings.Split(spec[4:], "/")
+	case strings.HasPrefix(spec, "#") && len(spec) == 7:
+		parts = []string{spec[1:3], spec[3:5], spec[5:7]}
+	}
+	if len(parts) != 3 {
+		return color.RGBA{}, fmt.Errorf("unsupported color %q", spec)
+	}
+	var v [3]byte
+	for i, h := range parts {
+		if len(h) < 1 || len(h) > 4 {
+			return color.RGBA{}, fmt.Errorf("unsupported color %q", spec)
+		}
+		n, err := strconv.ParseUint(h, 16, 16)
+		if err != nil {
+			return color.RGBA{}, fmt.Errorf("unsupported color %q", spec)
+		}
+		// Scale to 8 bits: h digits represent a fraction of 16^len(h)-1
+		max := uint64(1)<<(4*uint(len(h))) - 1
+		v[i] = byte((n*255 + max/2) / max)
+	}
+	return color.RGBA{R: v[0], G: v[1], B: v[2], A: 255}, nil
+}
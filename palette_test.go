@@ -0,0 +1,98 @@
+package ansi
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseXBINPalette(t *testing.T) {
+	b := make([]byte, 48)
+	// VGA attribute color 1 is blue, which is ANSI color 4
+	b[3*1+2] = 63
+	p, err := ParseXBINPalette(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := p[4]; c != (color.RGBA{B: 255, A: 255}) {
+		t.Errorf("Expected blue for color 4, got %+v", c)
+	}
+	if c := p[1]; c != (color.RGBA{A: 255}) {
+		t.Errorf("Expected black for color 1, got %+v", c)
+	}
+}
+
+func TestParseOSC4(t *testing.T) {
+	base := PaletteVGA()
+	p, err := ParseOSC4("\x1b]4;1;rgb:ff/80/0\x07\x1b]4;2;#102030;15;rgb:ffff/0000/ffff\x1b\\", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[int]color.RGBA{
+		0:  PaletteVGA()[0],
+		1:  {R: 255, G: 128, B: 0, A: 255},
+		2:  {R: 0x10, G: 0x20, B: 0x30, A: 255},
+		15: {R: 255, G: 0, B: 255, A: 255},
+	}
+	for i, want := range tests {
+		if p[i] != want {
+			t.Errorf("Color %d: expected %+v, got %+v", i, want, p[i])
+		}
+	}
+	if base[1] != VGAPalette[1] {
+		t.Error("Base palette was modified")
+	}
+	if _, err := ParseOSC4("\x1b]4;1;red\x07", PaletteVGA()); err == nil {
+		t.Error("Expected error for unsupported color")
+	}
+}
+
+func TestBuiltinPalettes(t *testing.T) {
+	for _, f := range []func() Palette{PaletteVGA, PaletteCGA, PaletteEGA, PaletteWorkbench, PaletteXterm, PaletteSolarized} {
+		p := f()
+		if len(p) != 16 {
+			t.Fatalf("Expected 16 colors, got %d", len(p))
+		}
+		want := p[0]
+		p[0] = color.RGBA{R: 1, A: 255}
+		if f()[0] != want {
+			t.Error("Modifying a built-in palette changed later copies")
+		}
+	}
+}
+
+func TestShortPalette(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	short := Palette{{A: 255}, red}
+	img := &Image{Width: 2, Height: 1, Pix: []Pixel{
+		{C: 0xdb, ForegroundColor: 1},
+		{C: 0xdb, ForegroundColor: 15, BackgroundColor: 9},
+	}}
+
+	pal := Render(img, &RenderOptions{Palette: short}).(*image.Paletted)
+	if len(pal.Palette) != 16 {
+		t.Errorf("Expected 16 colors, got %d", len(pal.Palette))
+	}
+	if c := pal.At(0, 0); c != red {
+		t.Errorf("Expected %v, got %v", red, c)
+	}
+	if c := pal.At(8, 0); c != VGAPalette[15] {
+		t.Errorf("Expected the VGA color %v, got %v", VGAPalette[15], c)
+	}
+	RenderThumbnail(img, 4, 4, &ThumbnailOptions{Render: &RenderOptions{Palette: short}, Method: ThumbnailCellAverage})
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, img, &HTMLOptions{Palette: short}); err != nil {
+		t.Error(err)
+	}
+	if err := WriteHTML(&buf, img, &HTMLOptions{Palette: short, InlineStyles: true}); err != nil {
+		t.Error(err)
+	}
+	if err := WriteSVG(&buf, img, &SVGOptions{Palette: short}); err != nil {
+		t.Error(err)
+	}
+	if err := WriteTerminal(&buf, img, &TerminalOptions{Palette: short, Colors: TerminalColorsTrueColor}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,483 @@
+package ansi
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+)
+
+// RenderOptions are the options for Render.
+type RenderOptions struct {
+	// Font is used to draw the characters. The default is FontVGA16.
+	Font *Font
+	// Face, if set, draws the characters instead of Font. Each character is
+	// mapped to a rune with Charset so any font.Face such as a TrueType or
+	// OpenType font can be used. The cell size is the advance of 'M' by the
+	// line height of the face. Glyphs are anti-aliased unless the output is
+	// paletted. LetterSpacing9 is ignored. See Font.Face to use a bitmap font.
+	// Cells with a rune from NewUTF8Parser draw the rune so any character
+	// the face has can be drawn. Other cells are mapped with Charset, and
+	// control characters are drawn as the glyphs they display as in text
+	// mode, such as '☺' for 1, unless Charset remaps them.
+	Face font.Face
+	// Charset maps characters to runes for Face. The default is the charset
+	// of the font named by the SAUCE record, if any, or CharsetCP437.
+	Charset *Charset
+	// LetterSpacing9 renders 9 pixel wide cells like VGA 720 pixel wide
+	// text mode. The 9th column repeats the 8th for the line graphics
+	// characters 0xC0-0xDF so box drawing stays connected, and is
+	// background for all other characters. Only used with 8 pixel wide fonts.
+	LetterSpacing9 bool
+	// AspectRatio stretches the output vertically by the given factor to
+	// correct for the non-square pixels of the displays the art was made
+	// on. 0 or 1 leaves the pixels square.
+	AspectRatio float64
+	// Filter selects how the output is stretched for AspectRatio.
+	Filter Filter
+	// Palette is the 16 colors used for the output. The default is VGAPalette.
+	Palette Palette
+	// Sauce, if set, provides defaults from the file's metadata. The font
+	// named by TInfoS is used if Font is nil, the letter spacing flag can
+	// enable LetterSpacing9, and the legacy aspect ratio flag sets the
+	// AspectRatio if it's 0. Use Sauce.Font to check that the font is
+	// available. For an unavailable IBM font the code page still sets the
+	// Charset used with Face.
+	Sauce *Sauce
+	// ColorModel selects the type of image returned by Render. The default
+	// ColorModelAuto returns an *image.RGBA only if the image has 24-bit colors.
+	ColorModel ColorModel
+	// Cells, if not empty, limits rendering to a rectangle of columns and
+	// rows. Render returns an image the size of the rectangle.
+	Cells image.Rectangle
+	// Cursor selects how the cursor of the image is drawn. The default is
+	// CursorNone. Only images with HasCursor set have a cursor and one
+	// hidden with ESC[?25l is never drawn. When the cursor is past the end
+	// of the text the image grows to include it.
+	Cursor CursorStyle
+	// CursorBlink makes RenderAnimatedGIF, EncodeAnimationGIF, and
+	// EncodeAnimationAPNG blink the cursor at the fast blink rate.
+	CursorBlink bool
+
+	// cursorOff leaves room for the cursor without drawing it, for the off
+	// frames of a blinking cursor.
+	cursorOff bool
+}
+
+// CursorStyle is the shape used to draw the cursor.
+type CursorStyle byte
+
+const (
+	CursorNone CursorStyle = iota
+	// CursorUnderline covers the bottom two scanlines of the cell like the VGA cursor.
+	CursorUnderline
+	// CursorBlock fills the whole cell.
+	CursorBlock
+	// CursorBar is a vertical bar at the left of the cell.
+	CursorBar
+)
+
+// ColorModel is the type of image produced by Render.
+type ColorModel byte
+
+const (
+	// ColorModelAuto uses ColorModelRGBA if any cell has a 24-bit color and
+	// ColorModelPaletted otherwise.
+	ColorModelAuto ColorModel = iota
+	// ColorModelPaletted produces an *image.Paletted using the palette. 24-bit
+	// colors are replaced by the closest color in the palette.
+	ColorModelPaletted
+	// ColorModelRGBA produces an *image.RGBA.
+	ColorModelRGBA
+)
+
+// Filter is the method used to scale images.
+type Filter byte
+
+const (
+	// FilterNearest repeats whole rows and keeps the output paletted.
+	FilterNearest Filter = iota
+	// FilterLinear blends between rows and produces an *image.RGBA.
+	FilterLinear
+)
+
+// legacyAspectRatio returns the stretch needed for 80 columns of 400 scanline
+// text mode to fill a 4:3 display: 1.35 for 9 pixel cells and 1.2 for 8.
+func legacyAspectRatio(cellWidth int) float64 {
+	return (float64(80*cellWidth) / 400) / (4.0 / 3.0)
+}
+
+// withDefaults returns a copy of the options with the defaults filled in.
+func (opts *RenderOptions) withDefaults() RenderOptions {
+	var o RenderOptions
+	if opts != nil {
+		o = *opts
+	}
+	if s := o.Sauce; s != nil {
+		if f, ok := LookupSauceFont(s.TInfoS); ok {
+			if o.Font == nil {
+				o.Font = f.Font
+			}
+			if o.Charset == nil {
+				o.Charset = f.Charset
+			}
+		} else {
+			if o.Charset == nil {
+				o.Charset = sauceCharset(s.TInfoS)
+			}
+			if o.Font == nil && o.Charset == CharsetLatin1 {
+				o.Font = fontVGA16Latin1()
+			}
+		}
+		if s.Flags&SauceFlagLetterSpacingMask == SauceFlagLetterSpacing9 {
+			o.LetterSpacing9 = true
+		}
+	}
+	if o.Font == nil {
+		o.Font = FontVGA16
+	}
+	if o.Charset == nil {
+		o.Charset = CharsetCP437
+	}
+	o.Palette = o.Palette.complete()
+	if s := o.Sauce; s != nil && o.AspectRatio == 0 && s.Flags&SauceFlagAspectRatioMask == SauceFlagAspectRatioLegacy {
+		o.AspectRatio = legacyAspectRatio(o.cellWidth())
+	}
+	return o
+}
+
+// cellWidth returns the width of a cell in pixels for the bitmap font.
+func (o *RenderOptions) cellWidth() int {
+	if o.LetterSpacing9 && o.Font.Width == 8 {
+		return 9
+	}
+	return o.Font.Width
+}
+
+// cellSize returns the size of a cell in pixels.
+func (o *RenderOptions) cellSize() image.Point {
+	if o.Face != nil {
+		return faceCellSize(o.Face)
+	}
+	return image.Pt(o.cellWidth(), o.Font.Height)
+}
+
+// Render rasterizes the image with each character drawn as a cell the size
+// of the font. The result is an *image.Paletted using the options' palette
+// unless the ColorModel is RGBA or the image is stretched with FilterLinear.
+func Render(ansiImage *Image, opts *RenderOptions) image.Image {
+	o := opts.withDefaults()
+	cells := o.cells(ansiImage)
+	size := o.cellSize()
+	rect := image.Rect(0, 0, cells.Dx()*size.X, cells.Dy()*size.Y)
+	var img draw.Image
+	if o.ColorModel == ColorModelRGBA || o.ColorModel == ColorModelAuto && ansiImage.hasRGB() {
+		img = image.NewRGBA(rect)
+	} else {
+		img = image.NewPaletted(rect, o.Palette.colors())
+	}
+	drawCells(img, image.Point{}, ansiImage, &o)
+	if o.AspectRatio > 0 && o.AspectRatio != 1 {
+		return stretchVertical(img, o.AspectRatio, o.Filter)
+	}
+	return img
+}
+
+// at returns the pixel at column x and row y, or the zero pixel outside the image.
+func (img *Image) at(x, y int) Pixel {
+	if x < 0 || y < 0 || x >= img.Width || y >= img.Height {
+		return Pixel{}
+	}
+	return img.Pix[y*img.Width+x]
+}
+
+// hasRGB returns true if any cell uses a 24-bit color.
+func (img *Image) hasRGB() bool {
+	for _, p := range img.Pix {
+		if p.ForegroundRGB.A != 0 || p.BackgroundRGB.A != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// DrawImage rasterizes ansiImage into dst with the top left corner of the
+// first cell at at. Drawing is clipped to the bounds of dst, and to
+// opts.Cells if set. AspectRatio and Filter are ignored. Drawing into an
+// *image.Paletted or *image.RGBA writes the pixels directly so a large
+// destination can be reused for many images such as a contact sheet.
+func DrawImage(dst draw.Image, at image.Point, ansiImage *Image, opts *RenderOptions) {
+	o := opts.withDefaults()
+	drawCells(dst, at, ansiImage, &o)
+}
+
+// cells returns the rectangle of cells to draw. It's the whole image
+// extended to include the cursor if it's drawn, limited to Cells if set.
+func (o *RenderOptions) cells(ansiImage *Image) image.Rectangle {
+	r := image.Rect(0, 0, ansiImage.Width, ansiImage.Height)
+	if c := ansiImage.Cursor; o.Cursor != CursorNone && ansiImage.cursorVisible() {
+		// The cursor is usually just past the end of the text
+		if c.X >= r.Max.X {
+			r.Max.X = c.X + 1
+		}
+		if c.Y >= r.Max.Y {
+			r.Max.Y = c.Y + 1
+		}
+	}
+	if !o.Cells.Empty() {
+		r = r.Intersect(o.Cells)
+	}
+	return r
+}
+
+// pixelSetter sets the pixel at x, y of an image to a palette index, or
+// to rgb if its alpha is not zero.
+type pixelSetter func(x, y int, c uint8, rgb color.RGBA)
+
+// newPixelSetter returns a pixelSetter for dst that maps indexes to colors
+// in pal. Only the low 4 bits of an index are used.
+func newPixelSetter(dst draw.Image, pal color.Palette) pixelSetter {
+	switch d := dst.(type) {
+	case *image.Paletted:
+		idx := make([]uint8, len(pal))
+		for i, c := range pal {
+			idx[i] = uint8(d.Palette.Index(c))
+		}
+		nearest := make(map[color.RGBA]uint8)
+		return func(x, y int, c uint8, rgb color.RGBA) {
+			if rgb.A != 0 {
+				n, ok := nearest[rgb]
+				if !ok {
+					n = uint8(d.Palette.Index(rgb))
+					nearest[rgb] = n
+				}
+				c = n
+			} else {
+				c = idx[c&15]
+			}
+			d.Pix[d.PixOffset(x, y)] = c
+		}
+	case *image.RGBA:
+		rgba := make([]color.RGBA, len(pal))
+		for i, c := range pal {
+			rgba[i] = color.RGBAModel.Convert(c).(color.RGBA)
+		}
+		return func(x, y int, c uint8, rgb color.RGBA) {
+			if rgb.A == 0 {
+				rgb = rgba[c&15]
+			}
+			i := d.PixOffset(x, y)
+			p := d.Pix[i : i+4 : i+4]
+			p[0] = rgb.R
+			p[1] = rgb.G
+			p[2] = rgb.B
+			p[3] = rgb.A
+		}
+	}
+	return func(x, y int, c uint8, rgb color.RGBA) {
+		if rgb.A != 0 {
+			dst.Set(x, y, rgb)
+		} else {
+			dst.Set(x, y, pal[c&15])
+		}
+	}
+}
+
+// drawCells draws the cells of ansiImage selected by o.Cells into dst at
+// at. The options must already have their defaults.
+func drawCells(dst draw.Image, at image.Point, ansiImage *Image, o *RenderOptions) {
+	font := o.Font
+	fontWidth := font.Width
+	size := o.cellSize()
+	cellWidth := size.X
+	fontHeight := size.Y
+	cells := o.cells(ansiImage)
+	set := newPixelSetter(dst, o.Palette.colors())
+	var fc *faceCells
+	_, paletted := dst.(*image.Paletted)
+	if o.Face != nil {
+		fc = newFaceCells(o)
+	}
+
+	// Pixel coordinates of the cells in dst
+	origin := at.Sub(image.Pt(cells.Min.X*cellWidth, cells.Min.Y*fontHeight))
+	clip := image.Rect(cells.Min.X*cellWidth, cells.Min.Y*fontHeight, cells.Max.X*cellWidth, cells.Max.Y*fontHeight).Add(origin).Intersect(dst.Bounds())
+	if clip.Empty() {
+		return
+	}
+	for y := cells.Min.Y; y < cells.Max.Y; y++ {
+		for x := cells.Min.X; x < cells.Max.X; x++ {
+			cell := image.Rect(x*cellWidth, y*fontHeight, (x+1)*cellWidth, (y+1)*fontHeight).Add(origin)
+			if !cell.Overlaps(clip) {
+				continue
+			}
+			p := ansiImage.at(x, y)
+			if fc != nil {
+				fc.drawCell(set, cell, clip, p, o.Palette, paletted)
+				continue
+			}
+			g := font.glyph(int(p.C))
+			lineGraphics := p.C >= 0xc0 && p.C <= 0xdf
+			for fy := 0; fy < fontHeight; fy++ {
+				py := cell.Min.Y + fy
+				if py < clip.Min.Y || py >= clip.Max.Y {
+					continue
+				}
+				for fx := 0; fx < cellWidth; fx++ {
+					px := cell.Min.X + fx
+					if px < clip.Min.X || px >= clip.Max.X {
+						continue
+					}
+					var on bool
+					if fx < fontWidth {
+						on = font.set(g, fx, fy)
+					} else if lineGraphics {
+						on = font.set(g, fontWidth-1, fy)
+					}
+					if on {
+						set(px, py, p.ForegroundColor, p.ForegroundRGB)
+					} else {
+						set(px, py, p.BackgroundColor, p.BackgroundRGB)
+					}
+				}
+			}
+		}
+	}
+	if o.Cursor != CursorNone && !o.cursorOff {
+		drawCursor(set, clip, origin, ansiImage, o.Cursor, cellWidth, fontHeight)
+	}
+}
+
+// cursorVisible returns true if the image has a cursor that isn't hidden.
+func (img *Image) cursorVisible() bool {
+	return img.HasCursor && !img.CursorHidden && img.Cursor.X >= 0 && img.Cursor.Y >= 0
+}
+
+// drawCursor draws the cursor of ansiImage in the foreground color of the
+// cell under it, or light gray for an empty cell.
+func drawCursor(set pixelSetter, clip image.Rectangle, origin image.Point, ansiImage *Image, style CursorStyle, cellWidth, cellHeight int) {
+	if !ansiImage.cursorVisible() {
+		return
+	}
+	c := ansiImage.Cursor
+	p := ansiImage.at(c.X, c.Y)
+	fg, rgb := p.ForegroundColor, p.ForegroundRGB
+	if p == (Pixel{}) {
+		fg = 7
+	}
+	r := image.Rect(0, 0, cellWidth, cellHeight)
+	switch style {
+	case CursorUnderline:
+		r.Min.Y = cellHeight - 2
+	case CursorBar:
+		r.Max.X = (cellWidth + 3) / 4
+	}
+	r = r.Add(image.Pt(c.X*cellWidth, c.Y*cellHeight).Add(origin)).Intersect(clip)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			set(x, y, fg, rgb)
+		}
+	}
+}
+
+// stretchVertical scales the height of img, an *image.Paletted or
+// *image.RGBA, by ratio.
+func stretchVertical(img draw.Image, ratio float64, filter Filter) image.Image {
+	b := img.Bounds()
+	height := int(math.Round(float64(b.Dy()) * ratio))
+	// row returns row y of the source as RGBA
+	var row func(y int, buf []byte) []byte
+	switch src := img.(type) {
+	case *image.Paletted:
+		if filter != FilterLinear {
+			out := image.NewPaletted(image.Rect(0, 0, b.Dx(), height), src.Palette)
+			stretchRows(out.Pix, out.Stride, src.Pix, src.Stride, b.Dx(), b.Dy(), ratio)
+			return out
+		}
+		pal := make([]color.RGBA, len(src.Palette))
+		for i, c := range src.Palette {
+			pal[i] = color.RGBAModel.Convert(c).(color.RGBA)
+		}
+		row = func(y int, buf []byte) []byte {
+			for x, c := range src.Pix[y*src.Stride : y*src.Stride+b.Dx()] {
+				p := pal[c]
+				buf[x*4+0] = p.R
+				buf[x*4+1] = p.G
+				buf[x*4+2] = p.B
+				buf[x*4+3] = p.A
+			}
+			return buf
+		}
+	case *image.RGBA:
+		if filter != FilterLinear {
+			out := image.NewRGBA(image.Rect(0, 0, b.Dx(), height))
+			stretchRows(out.Pix, out.Stride, src.Pix, src.Stride, b.Dx()*4, b.Dy(), ratio)
+			return out
+		}
+		row = func(y int, buf []byte) []byte {
+			return src.Pix[y*src.Stride : y*src.Stride+b.Dx()*4]
+		}
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), height))
+	buf0 := make([]byte, b.Dx()*4)
+	buf1 := make([]byte, b.Dx()*4)
+	for y := 0; y < height; y++ {
+		y0, y1, w := linearSourceRows(y, ratio, b.Dy())
+		r0 := row(y0, buf0)
+		r1 := row(y1, buf1)
+		lerpRow(out.Pix[y*out.Stride:], r0, r1, w)
+	}
+	return out
+}
+
+// stretchRows fills the rows of dst with the nearest rows of src. Rows are
+// n bytes long.
+func stretchRows(dst []byte, dstStride int, src []byte, srcStride, n, srcHeight int, ratio float64) {
+	for y := 0; y*dstStride < len(dst); y++ {
+		sy := nearestSourceRow(y, ratio, srcHeight)
+		copy(dst[y*dstStride:y*dstStride+n], src[sy*srcStride:])
+	}
+}
+
+// nearestSourceRow returns the source row for output row y when stretching by ratio.
+func nearestSourceRow(y int, ratio float64, srcHeight int) int {
+	sy := int((float64(y) + 0.5) / ratio)
+	if sy >= srcHeight {
+		sy = srcHeight - 1
+	}
+	return sy
+}
+
+// linearSourceRows returns the source rows blended for output row y when
+// stretching by ratio and the weight of y1.
+func linearSourceRows(y int, ratio float64, srcHeight int) (y0, y1 int, w float64) {
+	// Center of the output row in source coordinates
+	sy := (float64(y)+0.5)/ratio - 0.5
+	y0 = int(math.Floor(sy))
+	w = sy - float64(y0)
+	if y0 < 0 {
+		y0, w = 0, 0
+	}
+	y1 = y0 + 1
+	if y1 >= srcHeight {
+		y1 = srcHeight - 1
+	}
+	if y0 >= srcHeight {
+		y0 = srcHeight - 1
+	}
+	return y0, y1, w
+}
+
+// lerpRow blends the bytes of rows r0 and r1 into dst.
+func lerpRow(dst, r0, r1 []byte, w float64) {
+	for i := range r0 {
+		dst[i] = lerp8(r0[i], r1[i], w)
+	}
+}
+
+func lerp8(a, b byte, w float64) byte {
+	return byte(math.Round(float64(a)*(1-w) + float64(b)*w))
+}
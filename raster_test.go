@@ -0,0 +1,225 @@
+package ansi
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenderLetterSpacing9(t *testing.T) {
+	ansiImage := &Image{Width: 2, Height: 1, Pix: []Pixel{
+		{C: 0xc4, ForegroundColor: 7}, // ─
+		{C: 0xb3, ForegroundColor: 7}, // │
+	}}
+	img := Render(ansiImage, &RenderOptions{LetterSpacing9: true}).(*image.Paletted)
+	if w := img.Bounds().Dx(); w != 18 {
+		t.Fatalf("Expected width 18, got %d", w)
+	}
+	// The horizontal line is at row 7 of the 16 pixel font
+	if c := img.ColorIndexAt(8, 7); c != 7 {
+		t.Errorf("Expected 9th column of 0xc4 to be foreground, got %d", c)
+	}
+	for y := 0; y < 16; y++ {
+		if c := img.ColorIndexAt(17, y); c != 0 {
+			t.Errorf("Expected 9th column of 0xb3 to be background at row %d, got %d", y, c)
+		}
+	}
+}
+
+func TestRenderFontSize(t *testing.T) {
+	ansiImage := &Image{Width: 2, Height: 2, Pix: []Pixel{
+		{C: 0xdb, ForegroundColor: 15}, {C: 0xdb, ForegroundColor: 15},
+		{C: ' ', BackgroundColor: 4}, {C: ' ', BackgroundColor: 4},
+	}}
+	for _, f := range []*Font{FontVGA8, FontVGA14, FontVGA16, FontVGA19} {
+		if n := f.NumGlyphs(); n != 256 {
+			t.Errorf("%dx%d: expected 256 glyphs, got %d", f.Width, f.Height, n)
+		}
+		img := Render(ansiImage, &RenderOptions{Font: f}).(*image.Paletted)
+		if s := img.Bounds().Size(); s != image.Pt(16, 2*f.Height) {
+			t.Errorf("%dx%d: expected 16x%d, got %dx%d", f.Width, f.Height, 2*f.Height, s.X, s.Y)
+			continue
+		}
+		// The full block fills its cell and the next row starts right after it
+		if c := img.ColorIndexAt(15, f.Height-1); c != 15 {
+			t.Errorf("%dx%d: expected the last scanline of the first row to be foreground, got %d", f.Width, f.Height, c)
+		}
+		if c := img.ColorIndexAt(0, f.Height); c != 4 {
+			t.Errorf("%dx%d: expected the second row to start at %d, got %d", f.Width, f.Height, f.Height, c)
+		}
+	}
+}
+
+func TestRenderSauceFont(t *testing.T) {
+	ansiImage := &Image{Width: 1, Height: 1, Pix: []Pixel{{C: 'A', ForegroundColor: 7}}}
+	img := Render(ansiImage, &RenderOptions{Sauce: &Sauce{TInfoS: "IBM VGA50 437", Flags: SauceFlagLetterSpacing9}})
+	if s := img.Bounds().Size(); s.X != 9 || s.Y != 8 {
+		t.Errorf("Expected 9x8, got %dx%d", s.X, s.Y)
+	}
+}
+
+func TestRenderAspectRatio(t *testing.T) {
+	ansiImage := &Image{Width: 1, Height: 1, Pix: []Pixel{{C: 0xdb, ForegroundColor: 15, BackgroundColor: 0}}}
+	img := Render(ansiImage, &RenderOptions{AspectRatio: 1.35})
+	if _, ok := img.(*image.Paletted); !ok {
+		t.Errorf("Expected *image.Paletted for FilterNearest, got %T", img)
+	}
+	if h := img.Bounds().Dy(); h != 22 {
+		t.Errorf("Expected height 22, got %d", h)
+	}
+	img = Render(ansiImage, &RenderOptions{Sauce: &Sauce{Flags: SauceFlagAspectRatioLegacy}, Filter: FilterLinear})
+	if _, ok := img.(*image.RGBA); !ok {
+		t.Errorf("Expected *image.RGBA for FilterLinear, got %T", img)
+	}
+	if h := img.Bounds().Dy(); h != 19 {
+		t.Errorf("Expected height 19 for 8 pixel cells, got %d", h)
+	}
+}
+
+func TestRenderCursor(t *testing.T) {
+	tests := []struct {
+		src    string
+		cursor image.Point
+		size   image.Point
+	}{
+		{"hello", image.Pt(5, 0), image.Pt(6*8, 16)},
+		{"hello\r\n", image.Pt(0, 1), image.Pt(5*8, 2*16)},
+		{"hello\x1b[3D", image.Pt(2, 0), image.Pt(5*8, 16)},
+	}
+	for _, test := range tests {
+		ansiImage, err := Parse(strings.NewReader(test.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ansiImage.Cursor != test.cursor {
+			t.Fatalf("%q: expected cursor at %v, got %v", test.src, test.cursor, ansiImage.Cursor)
+		}
+		if size := RenderImage(ansiImage).Bounds().Size(); size != image.Pt(ansiImage.Width*8, ansiImage.Height*16) {
+			t.Errorf("%q: expected no room for the cursor without one, got %v", test.src, size)
+		}
+		img := Render(ansiImage, &RenderOptions{Cursor: CursorUnderline}).(*image.Paletted)
+		if size := img.Bounds().Size(); size != test.size {
+			t.Fatalf("%q: expected %v, got %v", test.src, test.size, size)
+		}
+		x, y := test.cursor.X*8, test.cursor.Y*16
+		if c := img.ColorIndexAt(x+4, y+15); c != 7 {
+			t.Errorf("%q: expected cursor on the bottom scanline, got %d", test.src, c)
+		}
+		if c := img.ColorIndexAt(x+4, y+13); c != 0 && test.cursor.X >= ansiImage.Width {
+			t.Errorf("%q: expected background above the cursor, got %d", test.src, c)
+		}
+	}
+
+	seq, err := NewParser(strings.NewReader("A\x1b[?25l")).ParseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hidden, err := NewRenderer().RenderSequence(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hidden.CursorHidden {
+		t.Fatal("Expected ESC[?25l to hide the cursor")
+	}
+	img := Render(hidden, &RenderOptions{Cursor: CursorBlock})
+	if size := img.Bounds().Size(); size != image.Pt(8, 16) {
+		t.Errorf("Expected no room for a hidden cursor, got %v", size)
+	}
+
+	// Images that didn't come from a Renderer have no cursor
+	noCursor := &Image{Width: 1, Height: 1, Pix: []Pixel{{C: ' ', BackgroundColor: 4}}}
+	if c := Render(noCursor, &RenderOptions{Cursor: CursorBlock}).(*image.Paletted).ColorIndexAt(4, 8); c != 4 {
+		t.Errorf("Expected no cursor on an image without one, got %d", c)
+	}
+}
+
+func TestDrawImage(t *testing.T) {
+	ansiImage := &Image{Width: 2, Height: 2, Pix: []Pixel{
+		{C: 0xdb, ForegroundColor: 1}, {C: 0xdb, ForegroundColor: 2},
+		{C: 0xdb, ForegroundColor: 3}, {C: 0xdb, ForegroundColor: 4},
+	}}
+	opts := &RenderOptions{Font: FontVGA8}
+	want := Render(ansiImage, opts)
+
+	dst := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	DrawImage(dst, image.Pt(10, 20), ansiImage, opts)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if got := dst.RGBAAt(x+10, y+20); got != VGAPalette[want.(*image.Paletted).ColorIndexAt(x, y)] {
+				t.Fatalf("Pixel %d,%d: expected %v, got %v", x, y, want.At(x, y), got)
+			}
+		}
+	}
+	if got := dst.RGBAAt(9, 20); got.A != 0 {
+		t.Errorf("Expected pixel outside the image to be untouched, got %v", got)
+	}
+
+	// Clipped to the bottom right cell
+	opts.Cells = image.Rect(1, 1, 2, 2)
+	pal := image.NewPaletted(image.Rect(0, 0, 8, 8), PaletteVGA().colors())
+	DrawImage(pal, image.Point{}, ansiImage, opts)
+	if c := pal.ColorIndexAt(0, 0); c != 4 {
+		t.Errorf("Expected color 4 at the top left, got %d", c)
+	}
+	if b := Render(ansiImage, opts).Bounds(); b.Dx() != 8 || b.Dy() != 8 {
+		t.Errorf("Expected an 8x8 image, got %v", b)
+	}
+}
+
+func TestRenderTrueColor(t *testing.T) {
+	orange := color.RGBA{200, 100, 0, 255}
+	ansiImage := &Image{Width: 2, Height: 1, Pix: []Pixel{
+		{C: 0xdb, ForegroundColor: 12, ForegroundRGB: orange},
+		{C: 0xdb, ForegroundColor: 4},
+	}}
+	img, ok := Render(ansiImage, nil).(*image.RGBA)
+	if !ok {
+		t.Fatal("Expected *image.RGBA for 24-bit colors")
+	}
+	if c := img.RGBAAt(0, 0); c != orange {
+		t.Errorf("Expected %v, got %v", orange, c)
+	}
+	if c := img.RGBAAt(8, 0); c != VGAPalette[4] {
+		t.Errorf("Expected %v, got %v", VGAPalette[4], c)
+	}
+
+	pal, ok := Render(ansiImage, &RenderOptions{ColorModel: ColorModelPaletted}).(*image.Paletted)
+	if !ok {
+		t.Fatal("Expected *image.Paletted for ColorModelPaletted")
+	}
+	if c := pal.ColorIndexAt(0, 0); c != 3 {
+		t.Errorf("Expected closest color 3 (brown), got %d", c)
+	}
+	if c := RenderImage(ansiImage).ColorIndexAt(0, 0); c != 3 {
+		t.Errorf("RenderImage: expected closest color 3 (brown), got %d", c)
+	}
+
+	ansiImage.Pix[0].ForegroundRGB = color.RGBA{}
+	if _, ok := Render(ansiImage, nil).(*image.Paletted); !ok {
+		t.Error("Expected *image.Paletted for 16 colors")
+	}
+}
+
+func TestRenderHighColorIndex(t *testing.T) {
+	// Only the low 4 bits of a color index are used
+	ansiImage := &Image{Width: 1, Height: 1, Pix: []Pixel{{C: 0xdc, ForegroundColor: 16 + 14, BackgroundColor: 0xf0 + 1}}}
+	if c := RenderImage(ansiImage).ColorIndexAt(0, 15); c != 14 {
+		t.Errorf("Expected foreground 14, got %d", c)
+	}
+	img := Render(ansiImage, &RenderOptions{ColorModel: ColorModelRGBA}).(*image.RGBA)
+	if c := img.RGBAAt(0, 0); c != VGAPalette[1] {
+		t.Errorf("Expected background %v, got %v", VGAPalette[1], c)
+	}
+	DrawImage(image.NewNRGBA(image.Rect(0, 0, 8, 16)), image.Point{}, ansiImage, nil)
+	RenderThumbnail(ansiImage, 4, 4, nil)
+	if err := RenderAnimatedGIF(io.Discard, ansiImage, nil); err != nil {
+		t.Error(err)
+	}
+	var buf bytes.Buffer
+	if err := EncodePNG(&buf, ansiImage, nil, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -2,11 +2,16 @@ package ansi
 
 import (
 	"fmt"
+	"image"
+	"image/color"
 )
 
+const defaultScreenWidth = 80
+
 type Renderer struct {
 	rows [][]Pixel
 	screenWidth int
+	screenHeight int // 0 for unbounded
 	row, col int
 	savedCursors [][2]int
 	fgBold byte
@@ -14,6 +19,11 @@ type Renderer struct {
 	bgColor byte
 	fgColor byte
 	blink Blink
+	fgRGB color.RGBA
+	bgRGB color.RGBA
+	fgNearest byte // closest VGA color to fgRGB
+	bgNearest byte // closest VGA color to bgRGB
+	cursorHidden bool
 }
 
 func NewRenderer() *Renderer {
@@ -22,13 +32,28 @@ func NewRenderer() *Renderer {
 	return r
 }
 
+// NewScreenRenderer returns a renderer that emulates a fixed size screen
+// such as 80x25. Moving past the bottom of the screen scrolls it, cursor
+// movement stops at the edges, and clearing the screen homes the cursor.
+// This is needed for animations which redraw the screen.
+func NewScreenRenderer(width, height int) *Renderer {
+	r := &Renderer{screenWidth: width, screenHeight: height}
+	r.Reset()
+	return r
+}
+
 func RenderSequence(seq []Sequence) (*Image, error) {
 	return NewRenderer().RenderSequence(seq)
 }
 
 func (r *Renderer) Reset() {
+	screenWidth := r.screenWidth
+	if screenWidth <= 0 {
+		screenWidth = defaultScreenWidth
+	}
 	*r = Renderer{
-		screenWidth: 80, // TODO: should be configurable and optional
+		screenWidth: screenWidth,
+		screenHeight: r.screenHeight,
 		row: 1,
 		col: 1,
 		fgBold: 0,
@@ -41,103 +66,244 @@ func (r *Renderer) Reset() {
 
 func (r *Renderer) RenderSequence(seq []Sequence) (*Image, error) {
 	for _, s := range seq {
-		switch s := s.(type) {
-		case Character:
-			switch s.C {
-			// case lf:
-			// 	row++
-			// case cr:
-			// 	col = 1
-			case lf:
-				r.row++
+		if err := r.Apply(s); err != nil {
+			return nil, err
+		}
+	}
+	return r.Image(), nil
+}
+
+// Apply renders a single sequence.
+func (r *Renderer) Apply(s Sequence) error {
+	switch s := s.(type) {
+	case Character:
+		switch s.C {
+		// case lf:
+		// 	row++
+		// case cr:
+		// 	col = 1
+		case lf:
+			r.row++
+			r.col = 1
+			r.scroll()
+		case cr:
+		default:
+			y := r.row - 1
+			x := r.col - 1
+			// TODO: Not sure how best to handle out of bounds values
+			if x < 0 {
+				x = 0
+			}
+			if y < 0 {
+				y = 0
+			}
+			for len(r.rows) <= y {
+				r.rows = append(r.rows, nil)
+			}
+			row := r.rows[y]
+			for len(row) <= x {
+				row = append(row, Pixel{})
+			}
+			r.rows[y] = row
+			// The closest VGA color is kept for output that can't use 24-bit colors
+			fg, bg := r.fgColor+r.fgBold, r.bgColor+r.bgBold
+			if r.fgRGB.A != 0 {
+				fg = r.fgNearest
+			}
+			if r.bgRGB.A != 0 {
+				bg = r.bgNearest
+			}
+			row[x] = Pixel{
+				C:               s.C,
+				R:               s.R,
+				ForegroundColor: fg,
+				BackgroundColor: bg,
+				Blink:           r.blink,
+				ForegroundRGB:   r.fgRGB,
+				BackgroundRGB:   r.bgRGB,
+				// TODO: attributes
+			}
+			r.col++
+		}
+	case Clear:
+		switch s.Type {
+		case ClearTypeScreen, ClearTypeScreenAndScrollback:
+			// TODO: reset row and col?
+			r.rows = r.rows[:0]
+			if r.screenHeight > 0 {
+				r.row = 1
 				r.col = 1
-			case cr:
-			default:
-				y := r.row - 1
-				x := r.col - 1
-				// TODO: Not sure how best to handle out of bounds values
-				if x < 0 {
-					x = 0
-				}
-				if y < 0 {
-					y = 0
-				}
-				for len(r.rows) <= y {
-					r.rows = append(r.rows, nil)
-				}
-				row := r.rows[y]
-				for len(row) <= x {
-					row = append(row, Pixel{})
-				}
-				r.rows[y] = row
-				row[x] = Pixel{
-					C:               s.C,
-					ForegroundColor: r.fgColor + r.fgBold,
-					BackgroundColor: r.bgColor + r.bgBold,
-					Blink:           r.blink,
-					// TODO: attributes
-				}
-				r.col++
 			}
-		case Clear:
-			switch s.Type {
-			case ClearTypeScreen:
-				// TODO: reset row and col?
-				r.rows = r.rows[:0]
-			default:
-				return nil, fmt.Errorf("unhandled clear type %d", s.Type)
+		case ClearTypeToEndOfScreen:
+			y := r.row - 1
+			r.erase(y, r.col-1, -1)
+			for y++; y < len(r.rows); y++ {
+				r.erase(y, 0, -1)
+			}
+		case ClearTypeToBeginningOfScreen:
+			for y := 0; y < r.row-1; y++ {
+				r.erase(y, 0, -1)
 			}
-		case CursorBackward:
-			r.col -= s.N
-		case CursorDown:
-			r.row += s.N
-		case CursorForward:
-			r.col += s.N
-		case CursorUp:
-			r.row -= s.N
-		case MoveCursorTo:
-			r.row = s.Row
-			r.col = s.Col
-		case RestoreCursorPosition:
-			x := r.savedCursors[len(r.savedCursors)-1]
-			r.savedCursors = r.savedCursors[:len(r.savedCursors)-1]
+			r.erase(r.row-1, 0, r.col)
+		default:
+			return fmt.Errorf("unhandled clear type %d", s.Type)
+		}
+	case CursorBackward:
+		r.col -= s.N
+		r.clampCursor()
+	case CursorDown:
+		r.row += s.N
+		r.clampCursor()
+	case CursorForward:
+		r.col += s.N
+		r.clampCursor()
+	case CursorUp:
+		r.row -= s.N
+		r.clampCursor()
+	case MoveCursorTo:
+		r.row = s.Row
+		r.col = s.Col
+		r.clampCursor()
+	case EraseLine:
+		from, to := 0, -1
+		switch s.Type {
+		case EraseLineToEnd:
+			from = r.col - 1
+		case EraseLineToBeginning:
+			to = r.col
+		}
+		r.erase(r.row-1, from, to)
+	case RestoreCursorPosition:
+		// Restoring without a saved position homes the cursor
+		r.row, r.col = 1, 1
+		if n := len(r.savedCursors); n != 0 {
+			x := r.savedCursors[n-1]
+			r.savedCursors = r.savedCursors[:n-1]
 			r.row = x[0]
 			r.col = x[1]
-		case SaveCursorPosition:
-			r.savedCursors = append(r.savedCursors, [2]int{r.row, r.col})
-		case SelectGraphicsRendition:
-			switch {
-			case s.N == GraphicsRenditionReset:
-				r.bgColor = 0
-				r.fgColor = 7
-				r.fgBold = 0
-				r.blink = BlinkNone
-			case s.N == GraphicsRenditionBold:
-				r.fgBold = 8
-			case s.N == GraphicsrenditionDefaultTextColor:
-				r.fgColor = 7
-				// TODO: should this also clear bold or not?
-				r.fgBold = 0
-			case s.N >= GraphicsRenditionSetTextColor0 && s.N <= GraphicsRenditionSetTextColor7:
-				r.fgColor = byte(s.N - GraphicsRenditionSetTextColor0)
-			case s.N >= GraphicsRenditionSetBackgroundColor0 && s.N <= GraphicsRenditionSetBackgroundColor7:
-				r.bgColor = byte(s.N - GraphicsRenditionSetBackgroundColor0)
-			case s.N == GraphicRenditionBlinkSlow:
-				r.blink = BlinkSlow
-			case s.N == GraphicRenditionBlinkFast:
-				r.blink = BlinkFast
-			default:
-				return nil, fmt.Errorf("unhandled graphics rendition %d", s.N)
-			}
+		}
+	case SetMode:
+		if s.Private && s.N == ModeShowCursor {
+			r.cursorHidden = false
+		}
+	case ResetMode:
+		if s.Private && s.N == ModeShowCursor {
+			r.cursorHidden = true
+		}
+	case SaveCursorPosition:
+		r.savedCursors = append(r.savedCursors, [2]int{r.row, r.col})
+	case SetTextColorRGB:
+		r.fgRGB = s.C
+		r.fgNearest = vgaPalette.nearest(s.C)
+	case SetBackgroundColorRGB:
+		r.bgRGB = s.C
+		r.bgNearest = vgaPalette.nearest(s.C)
+	case SelectGraphicsRendition:
+		switch {
+		case s.N == GraphicsRenditionReset:
+			r.bgColor = 0
+			r.fgColor = 7
+			r.fgBold = 0
+			r.bgBold = 0
+			r.blink = BlinkNone
+			r.fgRGB = color.RGBA{}
+			r.bgRGB = color.RGBA{}
+		case s.N == GraphicsRenditionBold:
+			r.fgBold = 8
+		case s.N == GraphicsrenditionDefaultTextColor:
+			r.fgColor = 7
+			// TODO: should this also clear bold or not?
+			r.fgBold = 0
+			r.fgRGB = color.RGBA{}
+		case s.N >= GraphicsRenditionSetTextColor0 && s.N <= GraphicsRenditionSetTextColor7:
+			r.fgColor = byte(s.N - GraphicsRenditionSetTextColor0)
+			r.fgRGB = color.RGBA{}
+		case s.N == GraphicsRenditionDefaultBackgroundColor:
+			r.bgColor = 0
+			r.bgBold = 0
+			r.bgRGB = color.RGBA{}
+		case s.N >= GraphicsRenditionSetBackgroundColor0 && s.N <= GraphicsRenditionSetBackgroundColor7:
+			r.bgColor = byte(s.N - GraphicsRenditionSetBackgroundColor0)
+			r.bgBold = 0
+			r.bgRGB = color.RGBA{}
+		case s.N >= GraphicsRenditionSetBrightTextColor0 && s.N <= GraphicsRenditionSetBrightTextColor7:
+			r.fgColor = byte(s.N - GraphicsRenditionSetBrightTextColor0)
+			r.fgBold = 8
+			r.fgRGB = color.RGBA{}
+		case s.N >= GraphicsRenditionSetBrightBackgroundColor0 && s.N <= GraphicsRenditionSetBrightBackgroundColor7:
+			r.bgColor = byte(s.N - GraphicsRenditionSetBrightBackgroundColor0)
+			r.bgBold = 8
+			r.bgRGB = color.RGBA{}
+		case s.N == GraphicRenditionBlinkSlow:
+			r.blink = BlinkSlow
+		case s.N == GraphicRenditionBlinkFast:
+			r.blink = BlinkFast
 		default:
-			return nil, fmt.Errorf("unhandled sequence %T", s)
+			return fmt.Errorf("unhandled graphics rendition %d", s.N)
 		}
-		for r.col > r.screenWidth {
-			r.col -= r.screenWidth
-			r.row++
+	default:
+		return fmt.Errorf("unhandled sequence %T", s)
+	}
+	for r.col > r.screenWidth {
+		r.col -= r.screenWidth
+		r.row++
+	}
+	r.scroll()
+	return nil
+}
+
+// erase clears columns from up to but not including to of row y, or to the
+// end of the row if to is negative. Erased cells become zero pixels rather
+// than taking the current background.
+func (r *Renderer) erase(y, from, to int) {
+	if y < 0 || y >= len(r.rows) {
+		return
+	}
+	row := r.rows[y]
+	if to < 0 || to > len(row) {
+		to = len(row)
+	}
+	if from < 0 {
+		from = 0
+	}
+	for x := from; x < to; x++ {
+		row[x] = Pixel{}
+	}
+}
+
+// scroll moves the screen contents up if the cursor has moved past the
+// bottom of a fixed size screen.
+func (r *Renderer) scroll() {
+	if r.screenHeight <= 0 {
+		return
+	}
+	for r.row > r.screenHeight {
+		if len(r.rows) != 0 {
+			r.rows = r.rows[1:]
 		}
+		r.row--
+	}
+}
+
+// clampCursor keeps the cursor on a fixed size screen.
+func (r *Renderer) clampCursor() {
+	if r.screenHeight <= 0 {
+		return
+	}
+	if r.row < 1 {
+		r.row = 1
+	} else if r.row > r.screenHeight {
+		r.row = r.screenHeight
+	}
+	if r.col < 1 {
+		r.col = 1
+	} else if r.col > r.screenWidth {
+		r.col = r.screenWidth
 	}
+}
 
+// Image returns the image rendered so far.
+func (r *Renderer) Image() *Image {
 	var width int
 	for _, r := range r.rows {
 		if len(r) > width {
@@ -145,6 +311,10 @@ func (r *Renderer) RenderSequence(seq []Sequence) (*Image, error) {
 		}
 	}
 	height := len(r.rows)
+	if r.screenHeight > 0 {
+		width = r.screenWidth
+		height = r.screenHeight
+	}
 
 	pix := make([]Pixel, 0, width*height)
 	for _, row := range r.rows {
@@ -153,13 +323,19 @@ func (r *Renderer) RenderSequence(seq []Sequence) (*Image, error) {
 			pix = append(pix, Pixel{})
 		}
 	}
+	for len(pix) < width*height {
+		pix = append(pix, Pixel{})
+	}
 
 	img := &Image{
-		Pix:    pix,
-		Width:  width,
-		Height: height,
+		Pix:          pix,
+		Width:        width,
+		Height:       height,
+		Cursor:       image.Point{X: r.col - 1, Y: r.row - 1},
+		CursorHidden: r.cursorHidden,
+		HasCursor:    true,
 	}
-	return img, nil
+	return img
 }
 
 // func (r *Renderer) RenderSequence(seq []Sequence) (*Image, error) {
@@ -0,0 +1,81 @@
+package ansi
+
+import (
+	"io"
+	"time"
+)
+
+// ReplayOptions are the options for Renderer.Replay.
+type ReplayOptions struct {
+	// Baud is the simulated line speed in bits per second. Each byte
+	// takes 10 bits (8N1). The default is 2400.
+	Baud int
+	// Interval, if not zero, limits events to at most one per interval of
+	// simulated time. Otherwise there's an event for every sequence.
+	Interval time.Duration
+}
+
+// Event is the state of the screen at a point during a replay.
+type Event struct {
+	// Time is when the screen reached this state at the simulated baud rate.
+	Time time.Duration
+	// Offset is the number of bytes of input consumed.
+	Offset int64
+	// Screen returns a copy of the screen. Copying is only done when it's
+	// called so it must be called before the event callback returns.
+	Screen func() *Image
+}
+
+// ByteTime returns how long it takes to receive n bytes at baud with 8N1
+// framing. A baud of 0 or less is treated as instantaneous.
+func ByteTime(n int64, baud int) time.Duration {
+	if baud <= 0 {
+		return 0
+	}
+	return time.Duration(n) * 10 * time.Second / time.Duration(baud)
+}
+
+// Replay renders the input of p as it would have appeared over a modem
+// and calls fn with the timestamped screen state. The final state is
+// always reported even if Interval would skip it. Replay stops at the end
+// of the input or when fn returns an error. Use NewScreenRenderer for a
+// fixed size screen.
+func (r *Renderer) Replay(p *Parser, opts *ReplayOptions, fn func(Event) error) error {
+	var o ReplayOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Baud <= 0 {
+		o.Baud = 2400
+	}
+
+	var next time.Duration
+	pending := false
+	for {
+		s, err := p.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err := r.Apply(s); err != nil {
+			return err
+		}
+		t := ByteTime(p.Offset(), o.Baud)
+		if o.Interval > 0 && t < next {
+			pending = true
+			continue
+		}
+		if err := fn(Event{Time: t, Offset: p.Offset(), Screen: r.Image}); err != nil {
+			return err
+		}
+		pending = false
+		if o.Interval > 0 {
+			next = (t/o.Interval + 1) * o.Interval
+		}
+	}
+	if pending {
+		return fn(Event{Time: ByteTime(p.Offset(), o.Baud), Offset: p.Offset(), Screen: r.Image})
+	}
+	return nil
+}
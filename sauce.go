@@ -0,0 +1,220 @@
+package ansi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SAUCE (Standard Architecture for Universal Comment Extensions) is the
+// metadata record appended to the end of most ANSI art files.
+// See http://www.acid.org/info/sauce/sauce.htm
+
+const (
+	sauceRecordSize  = 128
+	sauceCommentSize = 64
+	sauceDateFormat  = "20060102"
+)
+
+type SauceDataType byte
+
+const (
+	SauceDataTypeNone       SauceDataType = 0
+	SauceDataTypeCharacter  SauceDataType = 1
+	SauceDataTypeBitmap     SauceDataType = 2
+	SauceDataTypeVector     SauceDataType = 3
+	SauceDataTypeAudio      SauceDataType = 4
+	SauceDataTypeBinaryText SauceDataType = 5
+	SauceDataTypeXBin       SauceDataType = 6
+	SauceDataTypeArchive    SauceDataType = 7
+	SauceDataTypeExecutable SauceDataType = 8
+)
+
+// File types for SauceDataTypeCharacter
+const (
+	SauceFileTypeASCII      byte = 0
+	SauceFileTypeANSi       byte = 1
+	SauceFileTypeANSiMation byte = 2
+	SauceFileTypeRIPScript  byte = 3
+	SauceFileTypePCBoard    byte = 4
+	SauceFileTypeAvatar     byte = 5
+	SauceFileTypeHTML       byte = 6
+	SauceFileTypeSource     byte = 7
+	SauceFileTypeTundraDraw byte = 8
+)
+
+// Flags for SauceDataTypeCharacter and SauceDataTypeBinaryText
+const (
+	SauceFlagNonBlink          byte = 0x01 // iCE colors: blink bit is high intensity background
+	SauceFlagLetterSpacingMask byte = 0x06
+	SauceFlagLetterSpacing8    byte = 0x02
+	SauceFlagLetterSpacing9    byte = 0x04
+	SauceFlagAspectRatioMask   byte = 0x18
+	SauceFlagAspectRatioLegacy byte = 0x08 // stretch as on a legacy 4:3 display
+	SauceFlagAspectRatioSquare byte = 0x10
+)
+
+// ErrNoSauce is returned by ReadSauce when a file has no SAUCE record.
+var ErrNoSauce = errors.New("no SAUCE record")
+
+// Sauce is a SAUCE metadata record.
+type Sauce struct {
+	Title    string // 35 characters max
+	Author   string // 20 characters max
+	Group    string // 20 characters max
+	Date     time.Time
+	FileSize uint32 // size of the file not including the SAUCE record
+	DataType SauceDataType
+	FileType byte
+	TInfo1   uint16 // width in characters for character data
+	TInfo2   uint16 // height in lines for character data
+	TInfo3   uint16
+	TInfo4   uint16
+	Comments []string // 64 characters max each
+	Flags    byte
+	TInfoS   string // font name for character data, 22 characters max
+}
+
+// MarshalBinary returns the SAUCE record, preceded by the comment block
+// if there are any comments. It does not include the EOF marker that
+// separates the record from the file contents.
+func (s *Sauce) MarshalBinary() ([]byte, error) {
+	if len(s.Comments) > 255 {
+		return nil, fmt.Errorf("too many SAUCE comments (%d > 255)", len(s.Comments))
+	}
+	buf := make([]byte, 0, len(s.Comments)*sauceCommentSize+5+sauceRecordSize)
+	if len(s.Comments) != 0 {
+		buf = append(buf, "COMNT"...)
+		for _, c := range s.Comments {
+			buf = appendSauceString(buf, c, sauceCommentSize)
+		}
+	}
+	buf = append(buf, "SAUCE00"...)
+	buf = appendSauceString(buf, s.Title, 35)
+	buf = appendSauceString(buf, s.Author, 20)
+	buf = appendSauceString(buf, s.Group, 20)
+	if s.Date.IsZero() {
+		buf = appendSauceString(buf, "", 8)
+	} else {
+		buf = append(buf, s.Date.Format(sauceDateFormat)...)
+	}
+	var info [16]byte
+	binary.LittleEndian.PutUint32(info[0:4], s.FileSize)
+	info[4] = byte(s.DataType)
+	info[5] = s.FileType
+	binary.LittleEndian.PutUint16(info[6:8], s.TInfo1)
+	binary.LittleEndian.PutUint16(info[8:10], s.TInfo2)
+	binary.LittleEndian.PutUint16(info[10:12], s.TInfo3)
+	binary.LittleEndian.PutUint16(info[12:14], s.TInfo4)
+	info[14] = byte(len(s.Comments))
+	info[15] = s.Flags
+	buf = append(buf, info[:]...)
+	if len(s.TInfoS) > 22 {
+		return nil, fmt.Errorf("SAUCE TInfoS too long (%d > 22)", len(s.TInfoS))
+	}
+	// TInfoS is a zero terminated string rather than space padded
+	buf = append(buf, s.TInfoS...)
+	buf = append(buf, make([]byte, 22-len(s.TInfoS))...)
+	return buf, nil
+}
+
+// appendSauceString appends s space padded (or truncated) to n bytes.
+func appendSauceString(buf []byte, s string, n int) []byte {
+	if len(s) > n {
+		s = s[:n]
+	}
+	buf = append(buf, s...)
+	return append(buf, bytes.Repeat([]byte{' '}, n-len(s))...)
+}
+
+// UnmarshalBinary decodes a SAUCE record optionally preceded by its comment
+// block as returned by MarshalBinary.
+func (s *Sauce) UnmarshalBinary(b []byte) error {
+	if len(b) < sauceRecordSize {
+		return ErrNoSauce
+	}
+	comments := b[:len(b)-sauceRecordSize]
+	rec := b[len(b)-sauceRecordSize:]
+	if string(rec[:5]) != "SAUCE" {
+		return ErrNoSauce
+	}
+	*s = Sauce{
+		Title:    trimSauceString(rec[7:42]),
+		Author:   trimSauceString(rec[42:62]),
+		Group:    trimSauceString(rec[62:82]),
+		FileSize: binary.LittleEndian.Uint32(rec[90:94]),
+		DataType: SauceDataType(rec[94]),
+		FileType: rec[95],
+		TInfo1:   binary.LittleEndian.Uint16(rec[96:98]),
+		TInfo2:   binary.LittleEndian.Uint16(rec[98:100]),
+		TInfo3:   binary.LittleEndian.Uint16(rec[100:102]),
+		TInfo4:   binary.LittleEndian.Uint16(rec[102:104]),
+		Flags:    rec[105],
+		TInfoS:   trimSauceString(rec[106:128]),
+	}
+	// Dates are often garbage so ignore ones that don't parse
+	if d, err := time.Parse(sauceDateFormat, string(rec[82:90])); err == nil {
+		s.Date = d
+	}
+	// The comment block is optional even if there's a count so ignore it if it's missing
+	n := int(rec[104])
+	if size := 5 + n*sauceCommentSize; n != 0 && len(comments) >= size && string(comments[len(comments)-size:][:5]) == "COMNT" {
+		comments = comments[len(comments)-size+5:]
+		for i := 0; i < n; i++ {
+			s.Comments = append(s.Comments, trimSauceString(comments[i*sauceCommentSize:(i+1)*sauceCommentSize]))
+		}
+	}
+	return nil
+}
+
+// ReadSauce reads the SAUCE record from the end of a file. It returns
+// ErrNoSauce if the file doesn't have one.
+func ReadSauce(r io.ReadSeeker) (*Sauce, error) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	// Read enough for the maximum number of comments
+	size := int64(sauceRecordSize + 5 + 255*sauceCommentSize)
+	if size > end {
+		size = end
+	}
+	if _, err := r.Seek(end-size, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	s := &Sauce{}
+	if err := s.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// splitSauce returns the contents of a file without its SAUCE record,
+// comments, and EOF marker, along with the record if there is one.
+func splitSauce(b []byte) ([]byte, *Sauce) {
+	s := &Sauce{}
+	if err := s.UnmarshalBinary(b); err != nil {
+		return b, nil
+	}
+	end := len(b) - sauceRecordSize
+	if n := len(s.Comments); n != 0 {
+		end -= 5 + n*sauceCommentSize
+	}
+	if end > 0 && b[end-1] == eof {
+		end--
+	}
+	return b[:end], s
+}
+
+// trimSauceString strips the space or zero padding from a SAUCE string field.
+func trimSauceString(b []byte) string {
+	return strings.TrimRight(string(b), " \x00")
+}
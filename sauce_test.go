@@ -0,0 +1,120 @@
+package ansi
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSauceRoundTrip(t *testing.T) {
+	want := &Sauce{
+		Title:    "Title",
+		Author:   "Author",
+		Group:    "Group",
+		Date:     time.Date(1996, 4, 1, 0, 0, 0, 0, time.UTC),
+		FileSize: 1234,
+		DataType: SauceDataTypeCharacter,
+		FileType: SauceFileTypeANSi,
+		TInfo1:   80,
+		TInfo2:   25,
+		Comments: []string{"one", "two"},
+		Flags:    SauceFlagNonBlink | SauceFlagLetterSpacing9,
+		TInfoS:   "IBM VGA",
+	}
+	b, err := want.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append([]byte("data\x1a"), b...)
+	got, err := ReadSauce(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	if _, err := ReadSauce(bytes.NewReader([]byte("no sauce"))); err != ErrNoSauce {
+		t.Errorf("Expected ErrNoSauce, got %v", err)
+	}
+}
+
+func TestLookupSauceFont(t *testing.T) {
+	f, ok := LookupSauceFont("IBM EGA 437")
+	if !ok {
+		t.Fatal("IBM EGA 437 not found")
+	}
+	if f.Font != FontVGA14 || f.Charset != CharsetCP437 {
+		t.Errorf("Bad font for IBM EGA 437")
+	}
+	for cp, s := range codePages {
+		if n := len([]rune(s)); n != 128 {
+			t.Errorf("Code page %s has %d characters", cp, n)
+		}
+	}
+	for _, name := range []string{"IBM VGA 862", "Amiga Topaz 2+", "IBM XGA 850"} {
+		if _, ok := LookupSauceFont(name); ok {
+			t.Errorf("Expected %s not to be bundled", name)
+		}
+		if _, err := (&Sauce{TInfoS: name}).Font(); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+	if f, err := (&Sauce{TInfoS: "IBM VGA50 "}).Font(); err != nil || f.Font != FontVGA8 {
+		t.Errorf("Expected the 8x8 font for IBM VGA50, got %v %v", f, err)
+	}
+	if f, err := (&Sauce{TInfoS: "IBM VGA25G"}).Font(); err != nil || f.Font != FontVGA19 {
+		t.Errorf("Expected the 8x19 font for IBM VGA25G, got %v %v", f, err)
+	}
+	if f, err := (&Sauce{}).Font(); f != nil || err != nil {
+		t.Errorf("Expected no font without a name, got %v %v", f, err)
+	}
+
+	cs, ok := LookupCharset("850")
+	if !ok || cs[0x9b] != 'ø' || cs[0x41] != 'A' {
+		t.Error("Bad charset for code page 850")
+	}
+	for _, name := range []string{"IBM VGA 850", "IBM EGA 850", "IBM EGA43 850", "IBM VGA25G 850", "IBM VGA 866", "IBM VGA50 737"} {
+		f, err := (&Sauce{TInfoS: name}).Font()
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		base := ibmFonts[strings.ToLower(name[:strings.LastIndexByte(name, ' ')])]
+		if f.Font.Width != base.Width || f.Font.Height != base.Height || f.Charset != charsets[name[len(name)-3:]] {
+			t.Errorf("%s: expected a %dx%d font with the code page's charset", name, base.Width, base.Height)
+		}
+		if g, ok := LookupSauceFont(name); !ok || g != f {
+			t.Errorf("%s: expected the font to be built once", name)
+		}
+		// The lower half and the line graphics are the same as 437
+		for _, c := range []int{'A', 0xb3} {
+			if !bytes.Equal(f.Font.glyph(c), base.glyph(c)) {
+				t.Errorf("%s: expected the glyph for %#x to match code page 437", name, c)
+			}
+		}
+		// 0x80 is a letter in every code page: Ç, Α, or А
+		if bytes.Equal(f.Font.glyph(0x80), make([]byte, f.Font.Height)) {
+			t.Errorf("%s: expected a glyph for %q", name, f.Charset[0x80])
+		}
+	}
+	// 850 moves characters 437 has
+	f, _ = LookupSauceFont("IBM VGA 850")
+	if !bytes.Equal(f.Font.glyph(0xbd), FontVGA16.glyph(0x9b)) {
+		t.Error("Expected ¢ to use the glyph of 0x9b in code page 437")
+	}
+
+	o := (&RenderOptions{Sauce: &Sauce{TInfoS: "Amiga Topaz 2+"}}).withDefaults()
+	if o.Charset != CharsetLatin1 || !bytes.Equal(o.Font.glyph(0xe9), FontVGA16.glyph(0x82)) {
+		t.Error("Expected é to be drawn for Amiga art without the Amiga fonts")
+	}
+
+	topaz := &Font{Width: 8, Height: 8, Bitmap: make([]byte, 256*8)}
+	RegisterSauceFont("Amiga Topaz 2+", &SauceFont{Font: topaz, Charset: CharsetLatin1})
+	defer RegisterSauceFont("Amiga Topaz 2+", nil)
+	if f, err := (&Sauce{TInfoS: "Amiga Topaz 2+"}).Font(); err != nil || f.Font != topaz {
+		t.Errorf("Expected the registered font, got %v %v", f, err)
+	}
+}
@@ -0,0 +1,183 @@
+package ansi
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Charset maps the 256 characters of a code page to Unicode.
+type Charset [256]rune
+
+// CharsetCP437 is the IBM PC character set.
+var CharsetCP437 = (*Charset)(&PCASCIIToUnicode)
+
+// CharsetLatin1 is ISO-8859-1, the character set of the Amiga fonts.
+var CharsetLatin1 = func() *Charset {
+	cs := &Charset{}
+	for i := range cs {
+		cs[i] = rune(i)
+	}
+	return cs
+}()
+
+// SauceFont is a font named in the TInfoS field of a SAUCE record.
+type SauceFont struct {
+	Font    *Font
+	Charset *Charset
+}
+
+var (
+	sauceFontsMu sync.RWMutex
+	sauceFonts   = map[string]*SauceFont{}
+)
+
+// RegisterSauceFont adds or replaces the font for a SAUCE font name. This
+// can be used with ReadPSF, ReadRawFont, or ReadBDF to supply fonts that
+// aren't bundled with the package. A nil font removes the name.
+func RegisterSauceFont(name string, f *SauceFont) {
+	sauceFontsMu.Lock()
+	if f == nil {
+		delete(sauceFonts, strings.ToLower(name))
+	} else {
+		sauceFonts[strings.ToLower(name)] = f
+	}
+	sauceFontsMu.Unlock()
+}
+
+// LookupSauceFont returns the font for a SAUCE font name such as "IBM VGA50 437".
+// The IBM VGA, VGA50, VGA25G, EGA, and EGA43 fonts are bundled for every
+// code page in LookupCharset except 862. The fonts for code pages other
+// than 437 are built the first time they're looked up; see codePageFont
+// for how. The Amiga fonts and code page 862 must be registered with
+// RegisterSauceFont.
+func LookupSauceFont(name string) (*SauceFont, bool) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	sauceFontsMu.RLock()
+	f, ok := sauceFonts[key]
+	sauceFontsMu.RUnlock()
+	if ok {
+		return f, true
+	}
+	if f = ibmCodePageFont(key); f == nil {
+		return nil, false
+	}
+	sauceFontsMu.Lock()
+	if g, ok := sauceFonts[key]; ok {
+		f = g
+	} else {
+		sauceFonts[key] = f
+	}
+	sauceFontsMu.Unlock()
+	return f, true
+}
+
+// ibmFonts are the bundled fonts for the IBM SAUCE font names.
+var ibmFonts = map[string]*Font{
+	"ibm vga":    FontVGA16,
+	"ibm vga50":  FontVGA8,
+	"ibm vga25g": FontVGA19,
+	"ibm ega":    FontVGA14,
+	"ibm ega43":  FontVGA8,
+}
+
+// ibmCodePageFont returns the font for a lower case IBM SAUCE font name
+// with a code page other than 437, or nil if it can't be built. Go Mono,
+// which the missing glyphs come from, has no Hebrew so there's no font
+// for code page 862.
+func ibmCodePageFont(name string) *SauceFont {
+	i := strings.LastIndexByte(name, ' ')
+	if i < 0 {
+		return nil
+	}
+	base, cp := ibmFonts[name[:i]], name[i+1:]
+	if _, ok := codePages[cp]; base == nil || !ok || cp == "862" {
+		return nil
+	}
+	cs := charsets[cp]
+	return &SauceFont{Font: codePageFont(base, cs), Charset: cs}
+}
+
+// codePages are the upper halves of the supported code pages other than 437.
+// The lower half is the same as 437.
+var codePages = map[string]string{
+	"737": "ΑΒΓΔΕΖΗΘΙΚΛΜΝΞΟΠΡΣΤΥΦΧΨΩαβγδεζηθικλμνξοπρσςτυφχψ░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀ωάέήϊίόύϋώΆΈΉΊΌΎΏ±≥≤ΪΫ÷≈°∙·√ⁿ²■\u00a0",
+	"775": "ĆüéāäģåćłēŖŗīŹÄÅÉæÆōöĢ¢ŚśÖÜø£Ø×¤ĀĪóŻżź”¦©®¬½¼Ł«»░▒▓│┤ĄČĘĖ╣║╗╝ĮŠ┐└┴┬├─┼ŲŪ╚╔╩╦╠═╬Žąčęėįšųūž┘┌█▄▌▐▀ÓßŌŃõÕµńĶķĻļņĒŅ’\u00ad±“¾¶§÷„°∙·¹³²■\u00a0",
+	"850": "ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜø£Ø×ƒáíóúñÑªº¿®¬½¼¡«»░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐└┴┬├─┼ãÃ╚╔╩╦╠═╬¤ðÐÊËÈıÍÎÏ┘┌█▄¦Ì▀ÓßÔÒõÕµþÞÚÛÙýÝ¯´\u00ad±‗¾¶§÷¸°¨·¹³²■\u00a0",
+	"852": "ÇüéâäůćçłëŐőîŹÄĆÉĹĺôöĽľŚśÖÜŤťŁ×čáíóúĄąŽžĘę¬źČş«»░▒▓│┤ÁÂĚŞ╣║╗╝Żż┐└┴┬├─┼Ăă╚╔╩╦╠═╬¤đĐĎËďŇÍÎě┘┌█▄ŢŮ▀ÓßÔŃńňŠšŔÚŕŰýÝţ´\u00ad˝˛ˇ˘§÷¸°¨˙űŘř■\u00a0",
+	"855": "ђЂѓЃёЁєЄѕЅіІїЇјЈљЉњЊћЋќЌўЎџЏюЮъЪаАбБцЦдДеЕфФгГ«»░▒▓│┤хХиИ╣║╗╝йЙ┐└┴┬├─┼кК╚╔╩╦╠═╬¤лЛмМнНоОп┘┌█▄Пя▀ЯрРсСтТуУжЖвВьЬ№\u00adыЫзЗшШэЭщЩчЧ§■\u00a0",
+	"857": "ÇüéâäàåçêëèïîıÄÅÉæÆôöòûùİÖÜø£ØŞşáíóúñÑĞğ¿®¬½¼¡«»░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐└┴┬├─┼ãÃ╚╔╩╦╠═╬¤ºªÊËÈ\ufffdÍÎÏ┘┌█▄¦Ì▀ÓßÔÒõÕµ\ufffd×ÚÛÙìÿ¯´\u00ad±\ufffd¾¶§÷¸°¨·¹³²■\u00a0",
+	"858": "ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜø£Ø×ƒáíóúñÑªº¿®¬½¼¡«»░▒▓│┤ÁÂÀ©╣║╗╝¢¥┐└┴┬├─┼ãÃ╚╔╩╦╠═╬¤ðÐÊËÈ€ÍÎÏ┘┌█▄¦Ì▀ÓßÔÒõÕµþÞÚÛÙýÝ¯´\u00ad±‗¾¶§÷¸°¨·¹³²■\u00a0",
+	"860": "ÇüéâãàÁçêÊèÍÔìÃÂÉÀÈôõòÚùÌÕÜ¢£Ù₧ÓáíóúñÑªº¿Ò¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	"861": "ÇüéâäàåçêëèÐðÞÄÅÉæÆôöþûÝýÖÜø£Ø₧ƒáíóúÁÍÓÚ¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	"862": "אבגדהוזחטיךכלםמןנסעףפץצקרשת¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	"863": "ÇüéâÂà¶çêëèïî‗À§ÉÈÊôËÏûù¤ÔÜ¢£ÙÛƒ¦´óú¨¸³¯Î⌐¬½¼¾«»░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	"865": "ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜø£Ø₧ƒáíóúñÑªº¿⌐¬½¼¡«¤░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■\u00a0",
+	"866": "АБВГДЕЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯабвгдежзийклмноп░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀рстуфхцчшщъыьэюяЁёЄєЇїЎў°∙·√№¤■\u00a0",
+	"869": "\ufffd\ufffd\ufffd\ufffd\ufffd\ufffdΆ\ufffd·¬¦‘’Έ―ΉΊΪΌ\ufffd\ufffdΎΫ©Ώ²³ά£έήίϊΐόύΑΒΓΔΕΖΗ½ΘΙ«»░▒▓│┤ΚΛΜΝ╣║╗╝ΞΟ┐└┴┬├─┼ΠΡ╚╔╩╦╠═╬ΣΤΥΦΧΨΩαβγ┘┌█▄δε▀ζηθικλμνξοπρσςτ΄\u00ad±υφχ§ψ΅°¨ωϋΰώ■\u00a0",
+}
+
+var charsets = map[string]*Charset{"437": CharsetCP437, "819": CharsetLatin1}
+
+// LookupCharset returns the character set for an IBM code page number
+// such as "850". It can be used to register fonts for other code pages.
+func LookupCharset(codePage string) (*Charset, bool) {
+	cs, ok := charsets[codePage]
+	return cs, ok
+}
+
+func init() {
+	for cp, high := range codePages {
+		cs := *CharsetCP437
+		copy(cs[128:], []rune(high))
+		charsets[cp] = &cs
+	}
+
+	for name, f := range ibmFonts {
+		RegisterSauceFont(name, &SauceFont{Font: f, Charset: CharsetCP437})
+		RegisterSauceFont(name+" 437", &SauceFont{Font: f, Charset: CharsetCP437})
+	}
+}
+
+// Font returns the registered font named by TInfoS, or nil if TInfoS is
+// empty. It returns an error if the font isn't registered, in which case
+// Render uses the default font, or for the Amiga fonts the default font
+// arranged for Latin-1.
+func (s *Sauce) Font() (*SauceFont, error) {
+	name := strings.TrimSpace(s.TInfoS)
+	if name == "" {
+		return nil, nil
+	}
+	if f, ok := LookupSauceFont(name); ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("SAUCE font %q is not available", name)
+}
+
+// sauceCharset returns the character set for the code page at the end of
+// a SAUCE font name such as "IBM VGA 850", Latin-1 for the Amiga fonts, or
+// nil.
+func sauceCharset(name string) *Charset {
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, "Amiga ") {
+		return CharsetLatin1
+	}
+	if i := strings.LastIndexByte(name, ' '); i >= 0 && strings.HasPrefix(name, "IBM ") {
+		return charsets[name[i+1:]]
+	}
+	return nil
+}
+
+var (
+	latin1FontOnce sync.Once
+	latin1Font     *Font
+)
+
+// fontVGA16Latin1 returns FontVGA16 arranged for Latin-1. It draws Amiga
+// art with the right characters when the Amiga fonts aren't registered.
+func fontVGA16Latin1() *Font {
+	latin1FontOnce.Do(func() {
+		latin1Font = codePageFont(FontVGA16, CharsetLatin1)
+	})
+	return latin1Font
+}
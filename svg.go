@@ -0,0 +1,170 @@
+package ansi
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// SVGOptions are the options for WriteSVG.
+type SVGOptions struct {
+	// Paths draws glyphs as path outlines traced from the bitmap font
+	// rather than as text. The result looks identical to RenderImage
+	// at any scale but the text is no longer selectable.
+	Paths bool
+	// FontFamily is used for the text when not drawing paths. The default is "monospace".
+	FontFamily string
+	// Font is traced for the paths and sets the cell size. The default is FontVGA16.
+	Font *Font
+	// Palette is the 16 colors used for the output. The default is VGAPalette.
+	Palette Palette
+}
+
+// WriteSVG writes img as an SVG image with the same dimensions as the
+// output of RenderImage. Backgrounds are drawn as one rect for each run
+// of cells with the same color.
+func WriteSVG(w io.Writer, img *Image, opts *SVGOptions) error {
+	o := SVGOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.FontFamily == "" {
+		o.FontFamily = "monospace"
+	}
+	if o.Font == nil {
+		o.Font = FontVGA16
+	}
+	o.Palette = o.Palette.complete()
+	fontWidth := o.Font.Width
+	fontHeight := o.Font.Height
+
+	bw := bufio.NewWriter(w)
+	width := img.Width * fontWidth
+	height := img.Height * fontHeight
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="%s"/>`+"\n", width, height, cssColor(o.Palette[0]))
+
+	for y := 0; y < img.Height; y++ {
+		row := img.Pix[y*img.Width : (y+1)*img.Width]
+		for x := 0; x < len(row); {
+			bg, bgRGB := row[x].BackgroundColor&15, row[x].BackgroundRGB
+			n := 1
+			for x+n < len(row) && row[x+n].BackgroundColor&15 == bg && row[x+n].BackgroundRGB == bgRGB {
+				n++
+			}
+			if bg != 0 || bgRGB.A != 0 {
+				fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+					x*fontWidth, y*fontHeight, n*fontWidth, fontHeight, cssColor(row[x].background(o.Palette)))
+			}
+			x += n
+		}
+	}
+
+	if o.Paths {
+		// One path per foreground color and blink so the output stays small.
+		// 24-bit colors follow the palette colors in the order they appear.
+		var paths [16][BlinkFast + 1]strings.Builder
+		rgbPaths := make(map[svgPathKey]*strings.Builder)
+		var rgbKeys []svgPathKey
+		for y := 0; y < img.Height; y++ {
+			for x := 0; x < img.Width; x++ {
+				p := img.Pix[y*img.Width+x]
+				d := &paths[p.ForegroundColor&15][p.Blink]
+				if p.ForegroundRGB.A != 0 {
+					k := svgPathKey{p.ForegroundRGB, p.Blink}
+					if rgbPaths[k] == nil {
+						rgbPaths[k] = &strings.Builder{}
+						rgbKeys = append(rgbKeys, k)
+					}
+					d = rgbPaths[k]
+				}
+				fc := o.Font.glyph(int(p.C))
+				for fy := 0; fy < fontHeight; fy++ {
+					for fx := 0; fx < fontWidth; {
+						if !o.Font.set(fc, fx, fy) {
+							fx++
+							continue
+						}
+						n := 1
+						for fx+n < fontWidth && o.Font.set(fc, fx+n, fy) {
+							n++
+						}
+						fmt.Fprintf(d, "M%d %dh%dv1h-%dz", x*fontWidth+fx, y*fontHeight+fy, n, n)
+						fx += n
+					}
+				}
+			}
+		}
+		for fg := range paths {
+			for b := range paths[fg] {
+				if paths[fg][b].Len() == 0 {
+					continue
+				}
+				fmt.Fprintf(bw, `<path fill="%s" d="%s">`, cssColor(o.Palette[fg]), paths[fg][b].String())
+				closeSVGElement(bw, "path", Blink(b))
+			}
+		}
+		for _, k := range rgbKeys {
+			if d := rgbPaths[k]; d.Len() != 0 {
+				fmt.Fprintf(bw, `<path fill="%s" d="%s">`, cssColor(k.c), d.String())
+				closeSVGElement(bw, "path", k.blink)
+			}
+		}
+	} else {
+		fmt.Fprintf(bw, `<g font-family="%s" font-size="%d" xml:space="preserve">`+"\n", xmlEscape(o.FontFamily), fontHeight)
+		for y := 0; y < img.Height; y++ {
+			row := img.Pix[y*img.Width : (y+1)*img.Width]
+			for x := 0; x < len(row); {
+				p := row[x]
+				if p == (Pixel{}) {
+					x++
+					continue
+				}
+				n := 1
+				for x+n < len(row) && row[x+n] != (Pixel{}) &&
+					row[x+n].ForegroundColor&15 == p.ForegroundColor&15 && row[x+n].ForegroundRGB == p.ForegroundRGB && row[x+n].Blink == p.Blink {
+					n++
+				}
+				var text strings.Builder
+				for _, q := range row[x : x+n] {
+					text.WriteRune(q.text())
+				}
+				if s := strings.TrimRight(text.String(), " "); s != "" {
+					// The baseline is placed about where it is in the VGA fonts
+					fmt.Fprintf(bw, `<text x="%d" y="%d" textLength="%d" lengthAdjust="spacingAndGlyphs" fill="%s">%s`,
+						x*fontWidth, y*fontHeight+fontHeight*3/4, len([]rune(s))*fontWidth, cssColor(p.foreground(o.Palette)), xmlEscape(s))
+					closeSVGElement(bw, "text", p.Blink)
+				}
+				x += n
+			}
+		}
+		bw.WriteString("</g>\n")
+	}
+
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+// svgPathKey identifies the path for a 24-bit foreground color.
+type svgPathKey struct {
+	c     color.RGBA
+	blink Blink
+}
+
+// closeSVGElement adds the animation for blink if needed and closes the element.
+func closeSVGElement(w *bufio.Writer, name string, b Blink) {
+	if b != BlinkNone {
+		fmt.Fprintf(w, `<animate attributeName="visibility" values="visible;hidden" dur="%dms" calcMode="discrete" repeatCount="indefinite"/>`,
+			b.period().Milliseconds())
+	}
+	fmt.Fprintf(w, "</%s>\n", name)
+}
+
+var xmlReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func xmlEscape(s string) string {
+	return xmlReplacer.Replace(s)
+}
@@ -0,0 +1,105 @@
+package ansi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriteSVG(t *testing.T) {
+	img := &Image{Width: 4, Height: 1, Pix: []Pixel{
+		{C: 'A', ForegroundColor: 12, BackgroundColor: 1},
+		{C: '<', ForegroundColor: 12, BackgroundColor: 1},
+		{},
+		{C: 'B', ForegroundColor: 7, Blink: BlinkSlow},
+	}}
+	block := &Image{Width: 2, Height: 1, Pix: []Pixel{
+		{C: 0xdb, ForegroundColor: 2},
+		{C: 0xdb, ForegroundColor: 2, ForegroundRGB: color.RGBA{200, 100, 0, 255}, BackgroundRGB: color.RGBA{0, 0, 64, 255}, Blink: BlinkFast},
+	}}
+	// A full block is one run per scanline
+	var fullRows, rgbRows string
+	for y := 0; y < 8; y++ {
+		fullRows += fmt.Sprintf("M0 %dh8v1h-8z", y)
+		rgbRows += fmt.Sprintf("M8 %dh8v1h-8z", y)
+	}
+
+	tests := []struct {
+		name string
+		img  *Image
+		opts *SVGOptions
+		want []string
+		not  []string
+	}{
+		{
+			name: "text",
+			img:  img,
+			want: []string{
+				`<svg xmlns="http://www.w3.org/2000/svg" width="32" height="16" viewBox="0 0 32 16" shape-rendering="crispEdges">`,
+				`<rect width="32" height="16" fill="#000000"/>`,
+				`<rect x="0" y="0" width="16" height="16" fill="#aa0000"/>`,
+				`<g font-family="monospace" font-size="16" xml:space="preserve">`,
+				`<text x="0" y="12" textLength="16" lengthAdjust="spacingAndGlyphs" fill="#5555ff">A&lt;</text>`,
+				`<text x="24" y="12" textLength="8" lengthAdjust="spacingAndGlyphs" fill="#aaaaaa">B<animate attributeName="visibility" values="visible;hidden" dur="457ms" calcMode="discrete" repeatCount="indefinite"/></text>`,
+			},
+			not: []string{"<path"},
+		},
+		{
+			name: "font family",
+			img:  img,
+			opts: &SVGOptions{FontFamily: `"VGA" & co`},
+			want: []string{`<g font-family="&quot;VGA&quot; &amp; co"`},
+		},
+		{
+			name: "paths",
+			img:  block,
+			opts: &SVGOptions{Paths: true, Font: FontVGA8},
+			want: []string{
+				`<svg xmlns="http://www.w3.org/2000/svg" width="16" height="8" viewBox="0 0 16 8" shape-rendering="crispEdges">`,
+				`<rect x="8" y="0" width="8" height="8" fill="#000040"/>`,
+				`<path fill="#00aa00" d="` + fullRows + `"></path>`,
+				`<path fill="#c86400" d="` + rgbRows + `"><animate attributeName="visibility" values="visible;hidden" dur="228ms" calcMode="discrete" repeatCount="indefinite"/></path>`,
+			},
+			not: []string{"<text"},
+		},
+		{
+			name: "paths palette",
+			img:  block,
+			opts: &SVGOptions{Paths: true, Font: FontVGA8, Palette: PaletteSolarized()},
+			want: []string{
+				`<rect width="16" height="8" fill="#073642"/>`,
+				`<path fill="#859900" d="`,
+			},
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteSVG(&buf, test.img, test.opts); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, s := range test.want {
+			if !strings.Contains(out, s) {
+				t.Errorf("%s: expected %q in\n%s", test.name, s, out)
+			}
+		}
+		for _, s := range test.not {
+			if strings.Contains(out, s) {
+				t.Errorf("%s: unexpected %q in\n%s", test.name, s, out)
+			}
+		}
+		d := xml.NewDecoder(&buf)
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s: invalid XML: %s", test.name, err)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package ansi
+
+import (
+	"bufio"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// TerminalColors selects the color escape codes used by WriteTerminal.
+type TerminalColors byte
+
+const (
+	// TerminalColors16 uses the 8 standard and 8 high intensity (aixterm) colors.
+	// The actual colors depend on the terminal's theme.
+	TerminalColors16 TerminalColors = iota
+	// TerminalColors256 uses the closest color from the xterm 256 color cube and grayscale ramp.
+	TerminalColors256
+	// TerminalColorsTrueColor uses 24-bit colors.
+	TerminalColorsTrueColor
+)
+
+// TerminalOptions are the options for WriteTerminal.
+type TerminalOptions struct {
+	Colors TerminalColors
+	// NoBrightBackground maps high intensity background colors to their
+	// low intensity equivalent for terminals that don't support them.
+	// Only used with TerminalColors16.
+	NoBrightBackground bool
+	// Palette is the colors used for TerminalColors256 and
+	// TerminalColorsTrueColor. The default is VGAPalette. 24-bit colors are
+	// written as is for TerminalColorsTrueColor and as the closest color
+	// for TerminalColors256.
+	Palette Palette
+}
+
+// WriteTerminal writes img as UTF-8 text with escape codes for display on a
+// modern terminal. Unlike EncodeImage the output is not meant for DOS ANSI
+// viewers. Each line ends with a reset so colors don't bleed when the
+// terminal is wider than the image.
+func WriteTerminal(w io.Writer, img *Image, opts *TerminalOptions) error {
+	o := TerminalOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.Palette = o.Palette.complete()
+	bw := bufio.NewWriter(w)
+	var params []byte
+	for y := 0; y < img.Height; y++ {
+		row := img.Pix[y*img.Width : (y+1)*img.Width]
+		end := len(row)
+		for end > 0 && row[end-1] == (Pixel{}) {
+			end--
+		}
+		var last Pixel
+		for x, p := range row[:end] {
+			if p == (Pixel{}) {
+				p = Pixel{C: ' ', ForegroundColor: 7}
+			}
+			if x == 0 || p.ForegroundColor != last.ForegroundColor || p.BackgroundColor != last.BackgroundColor || p.Blink != last.Blink ||
+				p.ForegroundRGB != last.ForegroundRGB || p.BackgroundRGB != last.BackgroundRGB {
+				params = append(params[:0], "\x1b[0;"...)
+				params = o.appendColor(params, p.ForegroundColor, p.ForegroundRGB, false)
+				params = append(params, ';')
+				params = o.appendColor(params, p.BackgroundColor, p.BackgroundRGB, true)
+				switch p.Blink {
+				case BlinkSlow:
+					params = append(params, ";5"...)
+				case BlinkFast:
+					params = append(params, ";6"...)
+				}
+				params = append(params, 'm')
+				bw.Write(params)
+				last = p
+			}
+			bw.WriteRune(p.text())
+		}
+		if end != 0 {
+			bw.WriteString("\x1b[0m")
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// appendColor appends the parameters for a color. 24-bit colors (rgb with
+// a non-zero alpha) fall back to the color index c for TerminalColors16.
+func (o *TerminalOptions) appendColor(b []byte, c byte, rgb color.RGBA, background bool) []byte {
+	c &= 15
+	if rgb.A == 0 {
+		rgb = o.Palette[c]
+	}
+	switch o.Colors {
+	case TerminalColors256:
+		if background {
+			b = append(b, "48;5;"...)
+		} else {
+			b = append(b, "38;5;"...)
+		}
+		return strconv.AppendInt(b, int64(xterm256Index(rgb)), 10)
+	case TerminalColorsTrueColor:
+		if background {
+			b = append(b, "48;2;"...)
+		} else {
+			b = append(b, "38;2;"...)
+		}
+		b = strconv.AppendInt(b, int64(rgb.R), 10)
+		b = append(b, ';')
+		b = strconv.AppendInt(b, int64(rgb.G), 10)
+		b = append(b, ';')
+		return strconv.AppendInt(b, int64(rgb.B), 10)
+	}
+	n := int(c & 7)
+	switch {
+	case background && c > 7 && o.NoBrightBackground:
+		n += int(GraphicsRenditionSetBackgroundColor0)
+	case background && c > 7:
+		n += int(GraphicsRenditionSetBrightBackgroundColor0)
+	case background:
+		n += int(GraphicsRenditionSetBackgroundColor0)
+	case c > 7:
+		n += int(GraphicsRenditionSetBrightTextColor0)
+	default:
+		n += int(GraphicsRenditionSetTextColor0)
+	}
+	return strconv.AppendInt(b, int64(n), 10)
+}
+
+// xterm256Color returns the default RGB value of an xterm 256 color index.
+func xterm256Color(i byte) color.RGBA {
+	switch {
+	case i < 16:
+		// The first 16 are the system colors which depend on the terminal theme.
+		// These are the xterm defaults.
+		return xtermSystemColors[i]
+	case i < 232:
+		i -= 16
+		return color.RGBA{R: xtermCubeLevel(i / 36), G: xtermCubeLevel(i / 6 % 6), B: xtermCubeLevel(i % 6), A: 255}
+	}
+	v := 8 + (i-232)*10
+	return color.RGBA{R: v, G: v, B: v, A: 255}
+}
+
+func xtermCubeLevel(i byte) byte {
+	if i == 0 {
+		return 0
+	}
+	return 55 + i*40
+}
+
+var xtermSystemColors = [16]color.RGBA{
+	{R: 0, G: 0, B: 0, A: 255},
+	{R: 205, G: 0, B: 0, A: 255},
+	{R: 0, G: 205, B: 0, A: 255},
+	{R: 205, G: 205, B: 0, A: 255},
+	{R: 0, G: 0, B: 238, A: 255},
+	{R: 205, G: 0, B: 205, A: 255},
+	{R: 0, G: 205, B: 205, A: 255},
+	{R: 229, G: 229, B: 229, A: 255},
+	{R: 127, G: 127, B: 127, A: 255},
+	{R: 255, G: 0, B: 0, A: 255},
+	{R: 0, G: 255, B: 0, A: 255},
+	{R: 255, G: 255, B: 0, A: 255},
+	{R: 92, G: 92, B: 255, A: 255},
+	{R: 255, G: 0, B: 255, A: 255},
+	{R: 0, G: 255, B: 255, A: 255},
+	{R: 255, G: 255, B: 255, A: 255},
+}
+
+// xterm256Index returns the closest color in the 6x6x6 color cube or
+// grayscale ramp. The system colors are skipped since they vary by terminal.
+func xterm256Index(c color.RGBA) byte {
+	best := byte(16)
+	bestDist := -1
+	for i := 16; i < 256; i++ {
+		x := xterm256Color(byte(i))
+		dr := int(x.R) - int(c.R)
+		dg := int(x.G) - int(c.G)
+		db := int(x.B) - int(c.B)
+		if d := dr*dr + dg*dg + db*db; bestDist < 0 || d < bestDist {
+			best = byte(i)
+			bestDist = d
+		}
+	}
+	return best
+}
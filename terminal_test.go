@@ -0,0 +1,41 @@
+package ansi
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+func TestWriteTerminal(t *testing.T) {
+	img := &Image{Width: 2, Height: 2, Pix: []Pixel{
+		{C: 'A', ForegroundColor: 12, BackgroundColor: 1, Blink: BlinkSlow}, {C: 0x82, ForegroundColor: 7},
+		{C: 'B', ForegroundColor: 7, BackgroundColor: 9}, {},
+	}}
+	rgb := &Image{Width: 1, Height: 1, Pix: []Pixel{
+		{C: 'C', ForegroundColor: 3, ForegroundRGB: color.RGBA{200, 100, 0, 255}, BackgroundColor: 4},
+	}}
+	tests := []struct {
+		name string
+		img  *Image
+		opts *TerminalOptions
+		want string
+	}{
+		{"16", img, nil, "\x1b[0;94;41;5mA\x1b[0;37;40mé\x1b[0m\n\x1b[0;37;101mB\x1b[0m\n"},
+		{"16 no bright background", img, &TerminalOptions{NoBrightBackground: true}, "\x1b[0;94;41;5mA\x1b[0;37;40mé\x1b[0m\n\x1b[0;37;41mB\x1b[0m\n"},
+		{"256", img, &TerminalOptions{Colors: TerminalColors256}, "\x1b[0;38;5;63;48;5;124;5mA\x1b[0;38;5;248;48;5;16mé\x1b[0m\n\x1b[0;38;5;248;48;5;203mB\x1b[0m\n"},
+		{"truecolor", img, &TerminalOptions{Colors: TerminalColorsTrueColor}, "\x1b[0;38;2;85;85;255;48;2;170;0;0;5mA\x1b[0;38;2;170;170;170;48;2;0;0;0mé\x1b[0m\n\x1b[0;38;2;170;170;170;48;2;255;85;85mB\x1b[0m\n"},
+		{"truecolor palette", img, &TerminalOptions{Colors: TerminalColorsTrueColor, Palette: PaletteSolarized()}, "\x1b[0;38;2;131;148;150;48;2;220;50;47;5mA\x1b[0;38;2;238;232;213;48;2;7;54;66mé\x1b[0m\n\x1b[0;38;2;238;232;213;48;2;203;75;22mB\x1b[0m\n"},
+		{"24-bit as 16", rgb, nil, "\x1b[0;33;44mC\x1b[0m\n"},
+		{"24-bit as 256", rgb, &TerminalOptions{Colors: TerminalColors256}, "\x1b[0;38;5;166;48;5;19mC\x1b[0m\n"},
+		{"24-bit", rgb, &TerminalOptions{Colors: TerminalColorsTrueColor}, "\x1b[0;38;2;200;100;0;48;2;0;0;170mC\x1b[0m\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteTerminal(&buf, test.img, test.opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s: expected\n%q\ngot\n%q", test.name, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ansi
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TextOptions are the options for Text and TextLines.
+type TextOptions struct {
+	// DropDecorative removes shading, block, and box drawing characters,
+	// collapses the remaining whitespace, and skips lines left empty. What
+	// remains is the readable text such as group names and credits.
+	DropDecorative bool
+}
+
+// Text returns the characters of the image converted to Unicode with one
+// line per row. Trailing blanks and blank lines at the end are trimmed.
+func (img *Image) Text(opts *TextOptions) string {
+	lines := img.TextLines(opts)
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TextLines returns the characters of each row of the image converted to
+// Unicode with trailing blanks trimmed. Zero pixels are treated as spaces.
+func (img *Image) TextLines(opts *TextOptions) []string {
+	if opts == nil {
+		opts = &TextOptions{}
+	}
+	lines := make([]string, 0, img.Height)
+	var sb strings.Builder
+	for y := 0; y < img.Height; y++ {
+		sb.Reset()
+		for _, p := range img.Pix[y*img.Width : (y+1)*img.Width] {
+			if opts.DropDecorative && isDecorative(p.C) {
+				sb.WriteByte(' ')
+			} else {
+				sb.WriteRune(p.text())
+			}
+		}
+		line := strings.TrimRightFunc(sb.String(), unicode.IsSpace)
+		if opts.DropDecorative {
+			line = strings.Join(strings.Fields(line), " ")
+			if line == "" {
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// text returns the Unicode character of p.
+func (p Pixel) text() rune {
+	if p.R != 0 {
+		return p.R
+	}
+	return pcASCIIRune(p.C)
+}
+
+// isDecorative returns true for the shading, box drawing, and block characters.
+func isDecorative(c byte) bool {
+	return (c >= 176 && c <= 223) || c == 254
+}
@@ -0,0 +1,40 @@
+package ansi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	src := "\xc9\xcd\xcd\xcd\xcd\xbb\r\n" +
+		"\xba\xb1 Hi  \x82 \xba\r\n" +
+		"\xc8\xcd\xcd\xcd\xcd\xbc\r\n" +
+		"\xdb\xdb\xdb\r\n" +
+		"\x01 credits  \r\n"
+	img, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "╔════╗\n║▒ Hi  é ║\n╚════╝\n███\n☺ credits"
+	if got := img.Text(nil); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	blank := &Image{Width: 2, Height: 2, Pix: []Pixel{{C: 'a'}, {}, {}, {}}}
+	if got := blank.TextLines(nil); !reflect.DeepEqual(got, []string{"a", ""}) {
+		t.Errorf("Expected a line for every row, got %q", got)
+	}
+	if got := blank.Text(nil); got != "a" {
+		t.Errorf("Expected trailing blank lines to be trimmed, got %q", got)
+	}
+
+	opts := &TextOptions{DropDecorative: true}
+	if got, want := img.TextLines(opts), []string{"Hi é", "☺ credits"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DropDecorative: expected %q, got %q", want, got)
+	}
+	if got := img.Text(opts); got != "Hi é\n☺ credits" {
+		t.Errorf("DropDecorative: got %q", got)
+	}
+}
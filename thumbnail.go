@@ -0,0 +1,298 @@
+package ansi
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// ThumbnailMethod selects how RenderThumbnail reduces the image.
+type ThumbnailMethod byte
+
+const (
+	// ThumbnailAreaAverage rasterizes the glyphs a row of cells at a time
+	// and averages the pixels covered by each output pixel.
+	ThumbnailAreaAverage ThumbnailMethod = iota
+	// ThumbnailReducedCells rasterizes the glyphs scaled down to a cell
+	// close to the size they have in the thumbnail and then resamples that
+	// image to the exact size. It keeps most of the detail of
+	// ThumbnailAreaAverage without drawing every cell at full size.
+	ThumbnailReducedCells
+	// ThumbnailCellAverage replaces each cell with the average of its
+	// foreground and background weighted by how much of the cell the glyph
+	// covers. It's much faster and close to ThumbnailAreaAverage for large
+	// reductions but loses detail when there are only a few pixels per cell.
+	ThumbnailCellAverage
+)
+
+// ThumbnailOptions are the options for RenderThumbnail.
+type ThumbnailOptions struct {
+	// Render provides the font, palette, aspect ratio, and cells. Filter is ignored.
+	Render *RenderOptions
+	Method ThumbnailMethod
+	// FirstScreen crops the image to the first ScreenRows rows.
+	FirstScreen bool
+	// ScreenRows is the height of a screen for FirstScreen. The default is 25.
+	ScreenRows int
+}
+
+// RenderThumbnail renders a reduced copy of ansiImage that fits within
+// maxWidth by maxHeight pixels keeping its aspect ratio. A limit of 0 or
+// less leaves that dimension unconstrained. The image is never enlarged.
+// The full size image is never allocated so it's suitable for very long
+// files.
+func RenderThumbnail(ansiImage *Image, maxWidth, maxHeight int, opts *ThumbnailOptions) *image.RGBA {
+	var to ThumbnailOptions
+	if opts != nil {
+		to = *opts
+	}
+	if to.ScreenRows <= 0 {
+		to.ScreenRows = 25
+	}
+	o := to.Render.withDefaults()
+	cells := o.cells(ansiImage)
+	if to.FirstScreen && cells.Dy() > to.ScreenRows {
+		cells.Max.Y = cells.Min.Y + to.ScreenRows
+	}
+	o.Cells = cells
+	size := o.cellSize()
+	cellWidth := size.X
+	cellHeight := size.Y
+
+	srcWidth := cells.Dx() * cellWidth
+	srcHeight := cells.Dy() * cellHeight
+	if srcWidth == 0 || srcHeight == 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+	ratio := o.AspectRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	scale := 1.0
+	if maxWidth > 0 {
+		scale = math.Min(scale, float64(maxWidth)/float64(srcWidth))
+	}
+	if maxHeight > 0 {
+		scale = math.Min(scale, float64(maxHeight)/(float64(srcHeight)*ratio))
+	}
+	width := int(math.Max(1, math.Round(float64(srcWidth)*scale)))
+	height := int(math.Max(1, math.Round(float64(srcHeight)*ratio*scale)))
+
+	pal := o.Palette
+	var acc *areaAccumulator
+	switch to.Method {
+	case ThumbnailCellAverage:
+		acc = newAreaAccumulator(srcWidth, srcHeight, width, height)
+		row := make([]color.RGBA, srcWidth)
+		coverage := glyphCoverage(&o)
+		area := cellWidth * cellHeight
+		for y := cells.Min.Y; y < cells.Max.Y; y++ {
+			for x := cells.Min.X; x < cells.Max.X; x++ {
+				p := ansiImage.at(x, y)
+				c := blendCoverage(p.foreground(pal), p.background(pal), coverage[p.C], area)
+				for i := 0; i < cellWidth; i++ {
+					row[(x-cells.Min.X)*cellWidth+i] = c
+				}
+			}
+			for i := 0; i < cellHeight; i++ {
+				acc.addRow(row)
+			}
+		}
+	case ThumbnailReducedCells:
+		rw := int(math.Max(1, math.Round(float64(cellWidth)*scale)))
+		rh := int(math.Max(1, math.Round(float64(cellHeight)*ratio*scale)))
+		var masks [256][]byte
+		for c, m := range glyphMasks(&o) {
+			masks[c] = scaleMask(m, cellWidth, cellHeight, rw, rh)
+		}
+		acc = newAreaAccumulator(cells.Dx()*rw, cells.Dy()*rh, width, height)
+		row := make([]color.RGBA, cells.Dx()*rw)
+		for y := cells.Min.Y; y < cells.Max.Y; y++ {
+			for my := 0; my < rh; my++ {
+				for x := cells.Min.X; x < cells.Max.X; x++ {
+					p := ansiImage.at(x, y)
+					fg, bg := p.foreground(pal), p.background(pal)
+					m := masks[p.C][my*rw : (my+1)*rw]
+					for mx, a := range m {
+						row[(x-cells.Min.X)*rw+mx] = blendCoverage(fg, bg, int(a), 255)
+					}
+				}
+				acc.addRow(row)
+			}
+		}
+	default:
+		acc = newAreaAccumulator(srcWidth, srcHeight, width, height)
+		row := make([]color.RGBA, srcWidth)
+		band := image.NewRGBA(image.Rect(0, 0, srcWidth, cellHeight))
+		bo := o
+		for y := cells.Min.Y; y < cells.Max.Y; y++ {
+			bo.Cells = image.Rect(cells.Min.X, y, cells.Max.X, y+1)
+			drawCells(band, image.Point{}, ansiImage, &bo)
+			for by := 0; by < cellHeight; by++ {
+				for x := range row {
+					row[x] = band.RGBAAt(x, by)
+				}
+				acc.addRow(row)
+			}
+		}
+	}
+	return acc.image()
+}
+
+// glyphMasks returns the alpha of each pixel of the cell of each
+// character.
+func glyphMasks(o *RenderOptions) [256][]byte {
+	var masks [256][]byte
+	size := o.cellSize()
+	if o.Face != nil {
+		fc := newFaceCells(o)
+		for c := range masks {
+			if masks[c] = fc.coverage(charsetRune(fc.charset, byte(c))); masks[c] == nil {
+				masks[c] = make([]byte, size.X*size.Y)
+			}
+		}
+		return masks
+	}
+	font := o.Font
+	for c := range masks {
+		m := make([]byte, size.X*size.Y)
+		fc := font.glyph(c)
+		lineGraphics := c >= 0xc0 && c <= 0xdf
+		for fy := 0; fy < font.Height; fy++ {
+			for fx := 0; fx < size.X; fx++ {
+				if fx < font.Width && font.set(fc, fx, fy) || fx >= font.Width && lineGraphics && font.set(fc, font.Width-1, fy) {
+					m[fy*size.X+fx] = 0xff
+				}
+			}
+		}
+		masks[c] = m
+	}
+	return masks
+}
+
+// glyphCoverage returns the number of foreground pixels in the cell of each character.
+func glyphCoverage(o *RenderOptions) [256]int {
+	var coverage [256]int
+	for c, m := range glyphMasks(o) {
+		for _, a := range m {
+			coverage[c] += int(a)
+		}
+		coverage[c] /= 255
+	}
+	return coverage
+}
+
+// scaleMask box filters a width by height alpha mask to dw by dh.
+func scaleMask(mask []byte, width, height, dw, dh int) []byte {
+	acc := newAreaAccumulator(width, height, dw, dh)
+	row := make([]color.RGBA, width)
+	for y := 0; y < height; y++ {
+		for x := range row {
+			a := mask[y*width+x]
+			row[x] = color.RGBA{a, a, a, a}
+		}
+		acc.addRow(row)
+	}
+	img := acc.image()
+	scaled := make([]byte, dw*dh)
+	for i := range scaled {
+		scaled[i] = img.Pix[i*4+3]
+	}
+	return scaled
+}
+
+func blendCoverage(fg, bg color.RGBA, n, area int) color.RGBA {
+	mix := func(a, b byte) byte {
+		return byte((int(a)*n + int(b)*(area-n) + area/2) / area)
+	}
+	return color.RGBA{mix(fg.R, bg.R), mix(fg.G, bg.G), mix(fg.B, bg.B), mix(fg.A, bg.A)}
+}
+
+// areaAccumulator box filters rows of source pixels into a smaller or
+// larger image. Each source pixel is added to every output pixel it
+// overlaps weighted by the area of the overlap.
+type areaAccumulator struct {
+	srcHeight int
+	width     int
+	height    int
+	cols      [][]areaWeight // output columns overlapped by each source column
+	sums      [][4]uint64
+	weights   []uint64
+	y         int
+}
+
+// areaWeight is the overlap of a source pixel with output pixel i in units
+// of 1/(src*dst) of an output pixel.
+type areaWeight struct {
+	i int
+	w uint64
+}
+
+func newAreaAccumulator(srcWidth, srcHeight, width, height int) *areaAccumulator {
+	a := &areaAccumulator{
+		srcHeight: srcHeight,
+		width:     width,
+		height:    height,
+		cols:      make([][]areaWeight, srcWidth),
+		sums:      make([][4]uint64, width*height),
+		weights:   make([]uint64, width*height),
+	}
+	for x := range a.cols {
+		a.cols[x] = areaWeights(x, srcWidth, width)
+	}
+	return a
+}
+
+// areaWeights returns the output pixels overlapped by source pixel i and
+// the size of each overlap. Scaled by src and dst, source pixel i spans
+// [i*dst, (i+1)*dst) and output pixel j spans [j*src, (j+1)*src).
+func areaWeights(i, src, dst int) []areaWeight {
+	lo, hi := i*dst, (i+1)*dst
+	var weights []areaWeight
+	for j := lo / src; j*src < hi; j++ {
+		start, end := j*src, (j+1)*src
+		if start < lo {
+			start = lo
+		}
+		if end > hi {
+			end = hi
+		}
+		weights = append(weights, areaWeight{j, uint64(end - start)})
+	}
+	return weights
+}
+
+func (a *areaAccumulator) addRow(row []color.RGBA) {
+	rows := areaWeights(a.y, a.srcHeight, a.height)
+	a.y++
+	for _, ry := range rows {
+		sums := a.sums[ry.i*a.width : (ry.i+1)*a.width]
+		weights := a.weights[ry.i*a.width : (ry.i+1)*a.width]
+		for x, c := range row {
+			for _, rx := range a.cols[x] {
+				w := ry.w * rx.w
+				s := &sums[rx.i]
+				s[0] += uint64(c.R) * w
+				s[1] += uint64(c.G) * w
+				s[2] += uint64(c.B) * w
+				s[3] += uint64(c.A) * w
+				weights[rx.i] += w
+			}
+		}
+	}
+}
+
+func (a *areaAccumulator) image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, a.width, a.height))
+	for i, s := range a.sums {
+		n := a.weights[i]
+		if n == 0 {
+			continue
+		}
+		p := img.Pix[i*4 : i*4+4]
+		for j := range p {
+			p[j] = byte((s[j] + n/2) / n)
+		}
+	}
+	return img
+}
@@ -0,0 +1,69 @@
+package ansi
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestRenderThumbnail(t *testing.T) {
+	ansiImage := &Image{Width: 80, Height: 100, Pix: make([]Pixel, 80*100)}
+	for i := range ansiImage.Pix {
+		ansiImage.Pix[i] = Pixel{C: 0xdb, ForegroundColor: 4}
+	}
+	for _, method := range []ThumbnailMethod{ThumbnailAreaAverage, ThumbnailReducedCells, ThumbnailCellAverage} {
+		img := RenderThumbnail(ansiImage, 160, 0, &ThumbnailOptions{Method: method})
+		if s := img.Bounds().Size(); s != image.Pt(160, 400) {
+			t.Errorf("%d: expected 160x400, got %v", method, s)
+		}
+		if c := img.RGBAAt(80, 200); c != VGAPalette[4] {
+			t.Errorf("%d: expected %v, got %v", method, VGAPalette[4], c)
+		}
+
+		img = RenderThumbnail(ansiImage, 320, 200, &ThumbnailOptions{Method: method, FirstScreen: true})
+		if s := img.Bounds().Size(); s != image.Pt(320, 200) {
+			t.Errorf("%d: expected 320x200 for the first screen, got %v", method, s)
+		}
+	}
+
+	// Half of the cell is covered by the glyph
+	ansiImage = &Image{Width: 1, Height: 1, Pix: []Pixel{{C: 0xdc, ForegroundColor: 15, BackgroundColor: 0}}}
+	area := RenderThumbnail(ansiImage, 1, 1, nil).RGBAAt(0, 0)
+	cell := RenderThumbnail(ansiImage, 1, 1, &ThumbnailOptions{Method: ThumbnailCellAverage}).RGBAAt(0, 0)
+	reduced := RenderThumbnail(ansiImage, 1, 1, &ThumbnailOptions{Method: ThumbnailReducedCells}).RGBAAt(0, 0)
+	if area != cell || area != reduced || area.R < 0x70 || area.R > 0x90 {
+		t.Errorf("Expected half bright gray, got %v, %v, and %v", area, cell, reduced)
+	}
+
+	// At half size the bottom half block is a cell 8 pixels high with the
+	// edge of the block in the middle
+	img := RenderThumbnail(ansiImage, 4, 0, &ThumbnailOptions{Method: ThumbnailReducedCells})
+	if s := img.Bounds().Size(); s != image.Pt(4, 8) {
+		t.Fatalf("Expected 4x8, got %v", s)
+	}
+	if top, bottom := img.RGBAAt(2, 2), img.RGBAAt(2, 5); top != VGAPalette[0] || bottom != VGAPalette[15] {
+		t.Errorf("Expected %v above %v, got %v and %v", VGAPalette[0], VGAPalette[15], top, bottom)
+	}
+}
+
+func TestAreaAccumulator(t *testing.T) {
+	// Three source pixels into two: the middle one is split evenly
+	acc := newAreaAccumulator(3, 1, 2, 1)
+	acc.addRow([]color.RGBA{{0, 0, 0, 255}, {255, 255, 255, 255}, {0, 0, 0, 255}})
+	img := acc.image()
+	for x := 0; x < 2; x++ {
+		if c := img.RGBAAt(x, 0); c != (color.RGBA{85, 85, 85, 255}) {
+			t.Errorf("Pixel %d: expected a third of white, got %v", x, c)
+		}
+	}
+
+	// Two source pixels into three: the middle one is an even mix
+	acc = newAreaAccumulator(2, 1, 3, 1)
+	acc.addRow([]color.RGBA{{0, 0, 0, 255}, {255, 255, 255, 255}})
+	img = acc.image()
+	for x, want := range []byte{0, 128, 255} {
+		if c := img.RGBAAt(x, 0); c.R != want {
+			t.Errorf("Pixel %d: expected %d, got %d", x, want, c.R)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package ansi
+
+import (
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+var tundraMagic = []byte("\x18TUNDRA24")
+
+// TundraDraw commands. Any other byte is a character drawn with the
+// current colors.
+const (
+	tundraPosition   = 1 // 32-bit row and column
+	tundraForeground = 2 // character and 32-bit 0RGB foreground
+	tundraBackground = 4 // character and 32-bit 0RGB background
+	tundraBoth       = 6 // character, foreground, and background
+)
+
+const tundraWidth = 80
+
+// ReadTundra reads a TundraDraw file. The width is TInfo1 of the SAUCE
+// record if there is one and 80 otherwise. Cells have 24-bit colors so the
+// image renders through the RGBA path. ForegroundColor and BackgroundColor
+// are set to the closest VGA colors for output that can't use 24-bit
+// colors. Cells before the first color change are light gray on black.
+func ReadTundra(r io.Reader) (*Image, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b, sauce := splitSauce(b)
+	width := tundraWidth
+	if sauce != nil && sauce.TInfo1 != 0 {
+		width = int(sauce.TInfo1)
+	}
+	if len(b) < len(tundraMagic) || string(b[:len(tundraMagic)]) != string(tundraMagic) {
+		return nil, errors.New("not a TundraDraw file")
+	}
+	b = b[len(tundraMagic):]
+
+	errTruncated := errors.New("TundraDraw data truncated")
+	readColor := func(b []byte) color.RGBA {
+		v := binary.BigEndian.Uint32(b)
+		return color.RGBA{R: byte(v >> 16), G: byte(v >> 8), B: byte(v), A: 255}
+	}
+
+	var rows [][]Pixel
+	var row, col int
+	fg := Pixel{ForegroundColor: 7}
+	for i := 0; i < len(b); {
+		cmd := b[i]
+		i++
+		c := cmd
+		switch cmd {
+		case tundraPosition:
+			if i+8 > len(b) {
+				return nil, errTruncated
+			}
+			row = int(int32(binary.BigEndian.Uint32(b[i:])))
+			col = int(int32(binary.BigEndian.Uint32(b[i+4:])))
+			i += 8
+			// A row further than the remaining input is rejected so that
+			// a corrupt position can't allocate an unbounded number of rows.
+			if row < 0 || col < 0 || col >= width || row > len(rows)+len(b)-i {
+				return nil, errors.New("TundraDraw position out of range")
+			}
+			continue
+		case tundraForeground, tundraBackground:
+			if i+5 > len(b) {
+				return nil, errTruncated
+			}
+			c = b[i]
+			rgb := readColor(b[i+1:])
+			if cmd == tundraForeground {
+				fg.ForegroundRGB = rgb
+				fg.ForegroundColor = vgaPalette.nearest(rgb)
+			} else {
+				fg.BackgroundRGB = rgb
+				fg.BackgroundColor = vgaPalette.nearest(rgb)
+			}
+			i += 5
+		case tundraBoth:
+			if i+9 > len(b) {
+				return nil, errTruncated
+			}
+			c = b[i]
+			fg.ForegroundRGB = readColor(b[i+1:])
+			fg.ForegroundColor = vgaPalette.nearest(fg.ForegroundRGB)
+			fg.BackgroundRGB = readColor(b[i+5:])
+			fg.BackgroundColor = vgaPalette.nearest(fg.BackgroundRGB)
+			i += 9
+		}
+		for len(rows) <= row {
+			rows = append(rows, nil)
+		}
+		if rows[row] == nil {
+			rows[row] = make([]Pixel, width)
+		}
+		p := fg
+		p.C = c
+		rows[row][col] = p
+		col++
+		if col == width {
+			col = 0
+			row++
+		}
+	}
+
+	img := &Image{Width: width, Height: len(rows), Pix: make([]Pixel, width*len(rows))}
+	for y, r := range rows {
+		copy(img.Pix[y*width:], r)
+	}
+	return img, nil
+}
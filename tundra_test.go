@@ -0,0 +1,68 @@
+package ansi
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestReadTundra(t *testing.T) {
+	b := []byte("\x18TUNDRA24")
+	b = append(b, 'A')
+	b = append(b, 6, 'B', 0, 0xff, 0x80, 0, 0, 0, 0, 0x40)
+	b = append(b, 1, 0, 0, 0, 2, 0, 0, 0, 79)
+	b = append(b, 2, 'C', 0, 0, 0xff, 0)
+
+	img, err := ReadTundra(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 80 || img.Height != 3 {
+		t.Fatalf("Expected 80x3, got %dx%d", img.Width, img.Height)
+	}
+	if p := img.Pix[0]; p.C != 'A' || p.ForegroundColor != 7 || p.ForegroundRGB.A != 0 {
+		t.Errorf("Expected default colors for A, got %+v", p)
+	}
+	orange := color.RGBA{R: 0xff, G: 0x80, A: 255}
+	navy := color.RGBA{B: 0x40, A: 255}
+	if p := img.Pix[1]; p.C != 'B' || p.ForegroundRGB != orange || p.BackgroundRGB != navy {
+		t.Errorf("Expected orange on navy for B, got %+v", p)
+	}
+	if p := img.Pix[2*80+79]; p.C != 'C' || p.ForegroundRGB != (color.RGBA{G: 0xff, A: 255}) || p.BackgroundRGB != navy || p.ForegroundColor != 2 {
+		t.Errorf("Expected green on navy for C, got %+v", p)
+	}
+
+	if _, ok := Render(img, nil).(*image.RGBA); !ok {
+		t.Error("Expected Render to use RGBA for TundraDraw")
+	}
+}
+
+func TestReadTundraSauceWidth(t *testing.T) {
+	b := []byte("\x18TUNDRA24")
+	b = append(b, 1, 0, 0, 0, 1, 0, 0, 0, 39, 'A', 'B')
+	rec, err := (&Sauce{DataType: SauceDataTypeCharacter, FileType: 8, TInfo1: 40}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = append(append(b, eof), rec...)
+
+	img, err := ReadTundra(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 40 || img.Height != 3 {
+		t.Fatalf("Expected 40x3, got %dx%d", img.Width, img.Height)
+	}
+	if img.Pix[1*40+39].C != 'A' || img.Pix[2*40].C != 'B' {
+		t.Error("Expected A at the end of row 1 and B wrapped to row 2")
+	}
+}
+
+func TestReadTundraHugePosition(t *testing.T) {
+	b := []byte("\x18TUNDRA24")
+	b = append(b, 1, 0x7f, 0xff, 0xff, 0xff, 0, 0, 0, 0, 'A')
+	if _, err := ReadTundra(bytes.NewReader(b)); err == nil {
+		t.Error("Expected an error for a row past the end of the input")
+	}
+}
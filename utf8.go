@@ -0,0 +1,64 @@
+package ansi
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// NewUTF8Parser returns a parser for input encoded as UTF-8 rather than
+// PC ASCII. Each decoded character is returned with the rune in R and
+// the PC ASCII character with the same glyph in C, or '?' if there isn't
+// one. Bytes that aren't valid UTF-8 are parsed as PC ASCII.
+func NewUTF8Parser(r io.ByteReader) *Parser {
+	return &Parser{r: r, utf8: true}
+}
+
+// parseUTF8 parses the UTF-8 sequence starting with the current byte and
+// appends the character to seq. If the sequence isn't valid the byte is
+// appended as a PC ASCII character and any bytes read ahead are parsed
+// again.
+func (p *Parser) parseUTF8(seq []Sequence) []Sequence {
+	c := p.b
+	n := 0
+	switch {
+	case c&0xe0 == 0xc0:
+		n = 2
+	case c&0xf0 == 0xe0:
+		n = 3
+	case c&0xf8 == 0xf0:
+		n = 4
+	}
+	la := &lookahead{p: p, ok: n != 0}
+	for i := 1; i < n; i++ {
+		la.next(func(b byte) bool { return b&0xc0 == 0x80 })
+	}
+	if la.ok {
+		if r, size := utf8.DecodeRune(append([]byte{c}, la.buf...)); size == n {
+			return append(seq, Character{C: unicodeToPCASCII(r), R: r})
+		}
+	}
+	la.unread()
+	return append(seq, Character{C: c})
+}
+
+// pcASCIIFromUnicode maps the runes of the PC ASCII glyphs back to the
+// characters. Line feed and carriage return are left out since they move
+// the cursor rather than draw their glyphs.
+var pcASCIIFromUnicode = func() map[rune]byte {
+	m := make(map[rune]byte, 256)
+	for i := 255; i > 0; i-- {
+		if c := byte(i); c != lf && c != cr {
+			m[pcASCIIRune(c)] = c
+		}
+	}
+	return m
+}()
+
+// unicodeToPCASCII returns the PC ASCII character that displays as r, or
+// '?' if there isn't one.
+func unicodeToPCASCII(r rune) byte {
+	if c, ok := pcASCIIFromUnicode[r]; ok {
+		return c
+	}
+	return '?'
+}
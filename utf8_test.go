@@ -0,0 +1,83 @@
+package ansi
+
+import (
+	"bytes"
+	"image"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+)
+
+func TestUTF8Parser(t *testing.T) {
+	// Box drawing, a character CP437 doesn't have, a glyph of a line feed,
+	// an invalid byte, and a truncated sequence
+	src := "a\xe2\x94\x80\xe2\x82\xac\xe2\x97\x99\xff\x1b[31m\xe2\x94"
+	seq, err := NewUTF8Parser(bytes.NewReader([]byte(src))).ParseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Sequence{
+		Character{C: 'a'},
+		Character{C: 0xc4, R: '─'},
+		Character{C: '?', R: '€'},
+		Character{C: '?', R: '◙'},
+		Character{C: 0xff},
+		SelectGraphicsRendition{N: GraphicsRenditionSetTextColor1},
+		Character{C: 0xe2},
+		Character{C: 0x94},
+	}
+	if !reflect.DeepEqual(seq, want) {
+		t.Fatalf("Expected %+v, got %+v", want, seq)
+	}
+
+	img, err := RenderSequence(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Text(nil); got != "a─€◙\u00a0Γö" {
+		t.Errorf("Expected the decoded runes in the text, got %q", got)
+	}
+}
+
+func TestUTF8Face(t *testing.T) {
+	f, err := opentype.Parse(gomono.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: 16, DPI: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer face.Close()
+
+	seq, err := NewUTF8Parser(bytes.NewReader([]byte("€?"))).ParseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := RenderSequence(seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Pix[0].C != img.Pix[1].C {
+		t.Fatalf("Expected '?' as the PC ASCII character for €, got %q", img.Pix[0].C)
+	}
+	// The face draws the rune while the bitmap font can only draw '?'
+	opts := &RenderOptions{Face: face, ColorModel: ColorModelRGBA}
+	rgba := Render(img, opts).(*image.RGBA)
+	o := opts.withDefaults()
+	w := o.cellSize().X
+	same := true
+	for y := 0; y < rgba.Bounds().Dy() && same; y++ {
+		for x := 0; x < w; x++ {
+			if rgba.RGBAAt(x, y) != rgba.RGBAAt(x+w, y) {
+				same = false
+				break
+			}
+		}
+	}
+	if same {
+		t.Error("Expected € to be drawn differently from '?'")
+	}
+}
@@ -0,0 +1,327 @@
+package ansi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Document is an image together with the font and palette stored with it
+// by formats such as XBIN.
+type Document struct {
+	Image *Image
+	// Font is the embedded font or nil for the default.
+	Font *Font
+	// Palette is the embedded palette or nil for the default.
+	Palette Palette
+	// ICEColors is true if the high bit of the attribute selects a high
+	// intensity background rather than blink.
+	ICEColors bool
+}
+
+// RenderOptions returns options for Render that use the document's font and palette.
+func (d *Document) RenderOptions() *RenderOptions {
+	return &RenderOptions{Font: d.Font, Palette: d.Palette}
+}
+
+var xbinMagic = []byte("XBIN\x1a")
+
+// XBIN header flags
+const (
+	xbinFlagPalette  = 1 << 0
+	xbinFlagFont     = 1 << 1
+	xbinFlagCompress = 1 << 2
+	xbinFlagNonBlink = 1 << 3
+	xbinFlag512Chars = 1 << 4
+)
+
+// XBIN compression types stored in the top 2 bits of a run's count byte.
+const (
+	xbinRunNone = 0 << 6
+	xbinRunChar = 1 << 6
+	xbinRunAttr = 2 << 6
+	xbinRunBoth = 3 << 6
+)
+
+// attrPixel returns the pixel for a character and VGA text mode attribute.
+// The high bit of the attribute is a high intensity background with iCE
+// colors and blink otherwise.
+func attrPixel(c, attr byte, ice bool) Pixel {
+	p := Pixel{
+		C:               c,
+		ForegroundColor: vgaToANSI[attr&15],
+		BackgroundColor: vgaToANSI[attr>>4&7],
+	}
+	if attr&0x80 != 0 {
+		if ice {
+			p.BackgroundColor += 8
+		} else {
+			p.Blink = BlinkSlow
+		}
+	}
+	return p
+}
+
+// pixelAttr returns the VGA text mode attribute for p. 24-bit colors are
+// replaced by the closest VGA color. Fast blink becomes blink.
+func pixelAttr(p Pixel, ice bool) (byte, error) {
+	attr := vgaToANSI[p.ForegroundColor&15] | vgaToANSI[p.BackgroundColor&7]<<4
+	switch {
+	case p.BackgroundColor&15 > 7 && !ice:
+		return 0, errors.New("high intensity background requires iCE colors")
+	case p.Blink != BlinkNone && ice:
+		return 0, errors.New("blink is not available with iCE colors")
+	case p.BackgroundColor&15 > 7 || p.Blink != BlinkNone:
+		attr |= 0x80
+	}
+	return attr, nil
+}
+
+// needsICEColors returns true if any pixel has a high intensity background.
+func (img *Image) needsICEColors() bool {
+	for _, p := range img.Pix {
+		if p.BackgroundColor&15 > 7 {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadXBIN reads an XBIN file. The 512 character mode is not supported.
+func ReadXBIN(r io.Reader) (*Document, error) {
+	br := bufio.NewReader(r)
+	var hdr [11]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hdr[:5], xbinMagic) {
+		return nil, errors.New("not an XBIN file")
+	}
+	width := int(binary.LittleEndian.Uint16(hdr[5:7]))
+	height := int(binary.LittleEndian.Uint16(hdr[7:9]))
+	fontHeight := int(hdr[9])
+	flags := hdr[10]
+	if flags&xbinFlag512Chars != 0 {
+		return nil, errors.New("XBIN 512 character mode is not supported")
+	}
+
+	doc := &Document{ICEColors: flags&xbinFlagNonBlink != 0}
+	if flags&xbinFlagPalette != 0 {
+		b := make([]byte, 48)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return nil, err
+		}
+		doc.Palette, _ = ParseXBINPalette(b)
+	}
+	if flags&xbinFlagFont != 0 {
+		if fontHeight < 1 || fontHeight > 32 {
+			return nil, fmt.Errorf("invalid XBIN font height %d", fontHeight)
+		}
+		b := make([]byte, 256*fontHeight)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return nil, err
+		}
+		doc.Font = &Font{Width: 8, Height: fontHeight, Bitmap: b}
+	}
+
+	// The image is built as the data is read so a header with a huge size
+	// can't allocate more than the input supplies.
+	n := width * height
+	doc.Image = &Image{Width: width, Height: height}
+	add := func(c, attr byte) {
+		doc.Image.Pix = append(doc.Image.Pix, attrPixel(c, attr, doc.ICEColors))
+	}
+	if flags&xbinFlagCompress != 0 {
+		if err := readXBINRuns(br, n, add); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+	row := make([]byte, width*2)
+	for y := 0; y < height; y++ {
+		if _, err := io.ReadFull(br, row); err != nil {
+			return nil, err
+		}
+		for x := 0; x < width; x++ {
+			add(row[x*2], row[x*2+1])
+		}
+	}
+	return doc, nil
+}
+
+// readXBINRuns decompresses n character and attribute pairs and calls add
+// with each.
+func readXBINRuns(r io.ByteReader, n int, add func(c, attr byte)) error {
+	for i := 0; i < n; {
+		b, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("XBIN data truncated: %w", err)
+		}
+		count := int(b&63) + 1
+		if i+count > n {
+			return errors.New("XBIN run extends past the end of the image")
+		}
+		var c, attr byte
+		switch b & 0xc0 {
+		case xbinRunChar:
+			c, err = r.ReadByte()
+		case xbinRunAttr:
+			attr, err = r.ReadByte()
+		case xbinRunBoth:
+			if c, err = r.ReadByte(); err == nil {
+				attr, err = r.ReadByte()
+			}
+		}
+		for ; count > 0 && err == nil; count-- {
+			switch b & 0xc0 {
+			case xbinRunNone:
+				if c, err = r.ReadByte(); err == nil {
+					attr, err = r.ReadByte()
+				}
+			case xbinRunChar:
+				attr, err = r.ReadByte()
+			case xbinRunAttr:
+				c, err = r.ReadByte()
+			}
+			if err == nil {
+				add(c, attr)
+				i++
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("XBIN data truncated: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteXBIN writes doc as a compressed XBIN file. iCE colors are used if
+// ICEColors is set or any pixel has a high intensity background, in which
+// case blinking isn't available. The font must be 8 pixels wide.
+func WriteXBIN(w io.Writer, doc *Document) error {
+	img := doc.Image
+	if img.Width > 0xffff || img.Height > 0xffff {
+		return fmt.Errorf("image size %dx%d is too large for XBIN", img.Width, img.Height)
+	}
+	ice := doc.ICEColors || img.needsICEColors()
+
+	var buf bytes.Buffer
+	buf.Write(xbinMagic)
+	var size [4]byte
+	binary.LittleEndian.PutUint16(size[0:2], uint16(img.Width))
+	binary.LittleEndian.PutUint16(size[2:4], uint16(img.Height))
+	buf.Write(size[:])
+	flags := byte(xbinFlagCompress)
+	fontHeight := 16
+	if doc.Palette != nil {
+		if len(doc.Palette) != 16 {
+			return fmt.Errorf("XBIN palette must have 16 colors, got %d", len(doc.Palette))
+		}
+		flags |= xbinFlagPalette
+	}
+	if f := doc.Font; f != nil {
+		if f.Width != 8 || f.Height < 1 || f.Height > 32 {
+			return fmt.Errorf("XBIN font must be 8x1 to 8x32, got %dx%d", f.Width, f.Height)
+		}
+		flags |= xbinFlagFont
+		fontHeight = f.Height
+	}
+	if ice {
+		flags |= xbinFlagNonBlink
+	}
+	buf.WriteByte(byte(fontHeight))
+	buf.WriteByte(flags)
+	if doc.Palette != nil {
+		for i := range doc.Palette {
+			c := doc.Palette[vgaToANSI[i]]
+			buf.Write([]byte{c.R >> 2, c.G >> 2, c.B >> 2})
+		}
+	}
+	if f := doc.Font; f != nil {
+		b := make([]byte, 256*f.Height)
+		copy(b, f.Bitmap)
+		buf.Write(b)
+	}
+
+	row := make([]byte, img.Width*2)
+	for y := 0; y < img.Height; y++ {
+		for x, p := range img.Pix[y*img.Width : (y+1)*img.Width] {
+			attr, err := pixelAttr(p, ice)
+			if err != nil {
+				return fmt.Errorf("row %d column %d: %w", y+1, x+1, err)
+			}
+			row[x*2], row[x*2+1] = p.C, attr
+		}
+		writeXBINRuns(&buf, row)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeXBINRuns compresses a row of character and attribute pairs. Runs
+// never cross rows.
+func writeXBINRuns(buf *bytes.Buffer, row []byte) {
+	n := len(row) / 2
+	for i := 0; i < n; {
+		c, attr := row[i*2], row[i*2+1]
+		// Find the longest run of each kind starting at i, up to 64 cells
+		sameChar, sameAttr, same := 1, 1, 1
+		for j := i + 1; j < n && j-i < 64 && row[j*2] == c; j++ {
+			sameChar++
+		}
+		for j := i + 1; j < n && j-i < 64 && row[j*2+1] == attr; j++ {
+			sameAttr++
+		}
+		for j := i + 1; j < n && j-i < 64 && row[j*2] == c && row[j*2+1] == attr; j++ {
+			same++
+		}
+		switch {
+		case same >= 2:
+			buf.WriteByte(xbinRunBoth | byte(same-1))
+			buf.WriteByte(c)
+			buf.WriteByte(attr)
+			i += same
+		case sameAttr >= 3:
+			buf.WriteByte(xbinRunAttr | byte(sameAttr-1))
+			buf.WriteByte(attr)
+			for j := i; j < i+sameAttr; j++ {
+				buf.WriteByte(row[j*2])
+			}
+			i += sameAttr
+		case sameChar >= 3:
+			buf.WriteByte(xbinRunChar | byte(sameChar-1))
+			buf.WriteByte(c)
+			for j := i; j < i+sameChar; j++ {
+				buf.WriteByte(row[j*2+1])
+			}
+			i += sameChar
+		default:
+			// Uncompressed until the next cell that starts a run
+			j := i + 1
+			for j < n && j-i < 64 && !xbinRunStarts(row, j, n) {
+				j++
+			}
+			buf.WriteByte(xbinRunNone | byte(j-i-1))
+			buf.Write(row[i*2 : j*2])
+			i = j
+		}
+	}
+}
+
+// xbinRunStarts returns true if a compressed run is worth starting at cell i.
+func xbinRunStarts(row []byte, i, n int) bool {
+	if i+1 >= n {
+		return false
+	}
+	if row[i*2] == row[i*2+2] && row[i*2+1] == row[i*2+3] {
+		return true
+	}
+	if i+2 >= n {
+		return false
+	}
+	return row[i*2+1] == row[i*2+3] && row[i*2+1] == row[i*2+5] ||
+		row[i*2] == row[i*2+2] && row[i*2] == row[i*2+4]
+}
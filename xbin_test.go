@@ -0,0 +1,78 @@
+package ansi
+
+import (
+	"bytes"
+	"image/color"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestXBINRoundTrip(t *testing.T) {
+	img := &Image{Width: 80, Height: 3, Pix: make([]Pixel, 80*3)}
+	for i := range img.Pix {
+		switch {
+		case i < 80:
+			img.Pix[i] = Pixel{C: 0xdb, ForegroundColor: 1, BackgroundColor: 12}
+		case i < 160:
+			img.Pix[i] = Pixel{C: byte('A' + i%26), ForegroundColor: 14, BackgroundColor: 4}
+		default:
+			img.Pix[i] = Pixel{C: byte(i), ForegroundColor: byte(i % 16), BackgroundColor: byte(i / 7 % 16)}
+		}
+	}
+	pal := PaletteVGA()
+	pal[1] = color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	want := &Document{Image: img, Font: FontVGA8, Palette: pal, ICEColors: true}
+
+	var buf bytes.Buffer
+	if err := WriteXBIN(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	if n := 11 + 48 + 256*8 + 80*3*2; buf.Len() >= n {
+		t.Errorf("Expected compression to make the file smaller than %d bytes, got %d", n, buf.Len())
+	}
+	got, err := ReadXBIN(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Document does not match after round trip")
+	}
+}
+
+func TestReadXBINUncompressed(t *testing.T) {
+	b := []byte("XBIN\x1a\x02\x00\x01\x00\x10\x00" + "A\x1fB\x9c")
+	doc, err := ReadXBIN(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Pixel{
+		{C: 'A', ForegroundColor: 15, BackgroundColor: 4},
+		{C: 'B', ForegroundColor: 9, BackgroundColor: 4, Blink: BlinkSlow},
+	}
+	if !reflect.DeepEqual(doc.Image.Pix, want) {
+		t.Errorf("Expected %+v, got %+v", want, doc.Image.Pix)
+	}
+	if doc.Font != nil || doc.Palette != nil || doc.ICEColors {
+		t.Errorf("Expected no font, palette, or iCE colors")
+	}
+
+	if err := WriteXBIN(&bytes.Buffer{}, &Document{Image: &Image{Width: 1, Height: 1, Pix: []Pixel{{BackgroundColor: 9, Blink: BlinkSlow}}}}); err == nil {
+		t.Error("Expected an error for blink with a high intensity background")
+	}
+}
+
+func TestReadXBINHugeHeader(t *testing.T) {
+	for _, flags := range []byte{0, xbinFlagCompress} {
+		b := []byte("XBIN\x1a\xff\xff\xff\xff\x10" + string(flags) + "\x01A\x07")
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if _, err := ReadXBIN(bytes.NewReader(b)); err == nil {
+			t.Errorf("flags %d: expected an error for truncated data", flags)
+		}
+		runtime.ReadMemStats(&after)
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+			t.Errorf("flags %d: allocated %d bytes for a 14 byte file", flags, n)
+		}
+	}
+}